@@ -0,0 +1,217 @@
+package msgpack
+
+import (
+	"fmt"
+	"time"
+)
+
+// Builder encodes values into a buffer and fills in the lengths of arrays and
+// maps automatically, so containers with a conditional number of elements
+// don't need to be counted up front:
+//
+//	var b msgpack.Builder
+//	b.BeginMap()
+//	b.AppendString("id")
+//	b.AppendInt(id)
+//	if name != "" {
+//		b.AppendString("name")
+//		b.AppendString(name)
+//	}
+//	b.EndMap()
+//	data := b.Bytes()
+//
+// Begin reserves a one byte header, which is enough for up to 15 elements.
+// Larger containers get a longer header on End and their contents are shifted
+// to make room, so Builder is best suited for small and medium containers; see
+// Encoder.AppendArrayStart for long streams.
+//
+// Mismatched Begin and End calls, map keys without values and unclosed
+// containers are programming errors and cause a panic.
+type Builder struct {
+	enc   Encoder
+	buf   []byte
+	stack []builderFrame
+}
+
+type builderFrame struct {
+	start int // index of the reserved header byte
+	n     int // number of values appended, keys and values counted separately
+	isMap bool
+}
+
+// NewBuilder returns a new Builder appending to dst.
+func NewBuilder(dst []byte) *Builder {
+	return &Builder{buf: dst}
+}
+
+// Reset discards any open containers and resets b to append to dst.
+func (b *Builder) Reset(dst []byte) {
+	b.buf = dst
+	b.stack = b.stack[:0]
+}
+
+// SetSortMapKeys is like Encoder.SetSortMapKeys.
+func (b *Builder) SetSortMapKeys(on bool) {
+	b.enc.SetSortMapKeys(on)
+}
+
+// UseCompactInts is like Encoder.UseCompactInts.
+func (b *Builder) UseCompactInts(on bool) {
+	b.enc.UseCompactInts(on)
+}
+
+// UseCompactFloats is like Encoder.UseCompactFloats.
+func (b *Builder) UseCompactFloats(on bool) {
+	b.enc.UseCompactFloats(on)
+}
+
+// Bytes returns the encoded data. It panics if a container is still open.
+func (b *Builder) Bytes() []byte {
+	if len(b.stack) > 0 {
+		panic(fmt.Sprintf("msgpack: Builder.Bytes with %d unclosed containers", len(b.stack)))
+	}
+	return b.buf
+}
+
+// Depth returns the number of open containers.
+func (b *Builder) Depth() int {
+	return len(b.stack)
+}
+
+// value counts a value appended to the innermost container.
+func (b *Builder) value() {
+	if top := len(b.stack) - 1; top >= 0 {
+		b.stack[top].n++
+	}
+}
+
+// BeginArray starts an array. The following values are its elements until the
+// matching EndArray.
+func (b *Builder) BeginArray() {
+	b.begin(false)
+}
+
+// EndArray closes the array started by the last unclosed BeginArray.
+func (b *Builder) EndArray() {
+	b.end(false)
+}
+
+// BeginMap starts a map. The following values are its keys and values,
+// alternating, until the matching EndMap.
+func (b *Builder) BeginMap() {
+	b.begin(true)
+}
+
+// EndMap closes the map started by the last unclosed BeginMap.
+func (b *Builder) EndMap() {
+	b.end(true)
+}
+
+func (b *Builder) begin(isMap bool) {
+	b.value()
+	b.stack = append(b.stack, builderFrame{start: len(b.buf), isMap: isMap})
+	b.buf = append(b.buf, 0)
+}
+
+func (b *Builder) end(isMap bool) {
+	top := len(b.stack) - 1
+	if top < 0 {
+		panic("msgpack: Builder.End without Begin")
+	}
+	f := b.stack[top]
+	if f.isMap != isMap {
+		if isMap {
+			panic("msgpack: Builder.EndMap closes an array")
+		}
+		panic("msgpack: Builder.EndArray closes a map")
+	}
+	b.stack = b.stack[:top]
+
+	var (
+		hdr [5]byte
+		h   []byte
+	)
+	if isMap {
+		if f.n%2 != 0 {
+			panic("msgpack: Builder.EndMap with a key without value")
+		}
+		h = b.enc.AppendMapLen(hdr[:0], f.n/2)
+	} else {
+		h = b.enc.AppendArrayLen(hdr[:0], f.n)
+	}
+
+	if extra := len(h) - 1; extra > 0 {
+		// Make room for the longer header.
+		end := len(b.buf)
+		b.buf = append(b.buf, h[:extra]...)
+		copy(b.buf[f.start+len(h):], b.buf[f.start+1:end])
+	}
+	copy(b.buf[f.start:], h)
+}
+
+// Append appends v like Encoder.Append.
+func (b *Builder) Append(v interface{}) {
+	b.value()
+	b.buf = b.enc.Append(b.buf, v)
+}
+
+// AppendRaw appends the already encoded value m verbatim, or nil if m is
+// empty.
+func (b *Builder) AppendRaw(m RawMessage) {
+	b.value()
+	if len(m) == 0 {
+		b.buf = b.enc.AppendNil(b.buf)
+		return
+	}
+	b.buf = append(b.buf, m...)
+}
+
+func (b *Builder) AppendNil() {
+	b.value()
+	b.buf = b.enc.AppendNil(b.buf)
+}
+
+func (b *Builder) AppendBool(value bool) {
+	b.value()
+	b.buf = b.enc.AppendBool(b.buf, value)
+}
+
+func (b *Builder) AppendInt(n int64) {
+	b.value()
+	b.buf = b.enc.AppendInt(b.buf, n)
+}
+
+func (b *Builder) AppendUint(n uint64) {
+	b.value()
+	b.buf = b.enc.AppendUint(b.buf, n)
+}
+
+func (b *Builder) AppendFloat32(n float32) {
+	b.value()
+	b.buf = b.enc.AppendFloat32(b.buf, n)
+}
+
+func (b *Builder) AppendFloat64(n float64) {
+	b.value()
+	b.buf = b.enc.AppendFloat64(b.buf, n)
+}
+
+func (b *Builder) AppendString(v string) {
+	b.value()
+	b.buf = b.enc.AppendString(b.buf, v)
+}
+
+func (b *Builder) AppendBytes(v []byte) {
+	b.value()
+	b.buf = b.enc.AppendBytes(b.buf, v)
+}
+
+func (b *Builder) AppendTime(tm time.Time) {
+	b.value()
+	b.buf = b.enc.AppendTime(b.buf, tm)
+}
+
+func (b *Builder) AppendDuration(d time.Duration) {
+	b.value()
+	b.buf = b.enc.AppendDuration(b.buf, d)
+}
@@ -0,0 +1,288 @@
+// Command msgpack inspects and converts msgpack data.
+//
+// Usage:
+//
+//	msgpack <command> [flags] [file]
+//
+// The input is read from file or, if it is omitted or "-", from stdin. It may
+// contain any number of concatenated msgpack values. The commands are:
+//
+//	json      print each value as JSON
+//	hex       print an annotated hex dump
+//	validate  check that the input is well-formed
+//	fromjson  convert a stream of JSON values to msgpack
+//	split     write each value to a separate file
+//
+// Run msgpack <command> -h for the flags of a command.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nurlybekovnt/msgpack"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"json", "print each value as JSON", runJSON},
+	{"hex", "print an annotated hex dump", runHex},
+	{"validate", "check that the input is well-formed", runValidate},
+	{"fromjson", "convert a stream of JSON values to msgpack", runFromJSON},
+	{"split", "write each value to a separate file", runSplit},
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := flag.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if err := cmd.run(flag.Args()[1:]); err != nil {
+				// Errors of the package already start with "msgpack: ".
+				if !strings.HasPrefix(err.Error(), "msgpack: ") {
+					err = fmt.Errorf("msgpack: %w", err)
+				}
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "msgpack: unknown command %q\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: msgpack <command> [flags] [file]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+// newFlagSet returns the flag set of a command taking an optional file
+// argument.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: msgpack %s [flags] [file]\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// openInput opens the file named by the arguments left after parsing fs.
+func openInput(fs *flag.FlagSet) (io.ReadCloser, error) {
+	switch fs.NArg() {
+	case 0:
+		return io.NopCloser(os.Stdin), nil
+	case 1:
+		if name := fs.Arg(0); name != "-" {
+			return os.Open(name)
+		}
+		return io.NopCloser(os.Stdin), nil
+	}
+	fs.Usage()
+	os.Exit(2)
+	return nil, nil
+}
+
+// readInput reads the whole input, for the commands that can't stream it.
+func readInput(fs *flag.FlagSet) ([]byte, error) {
+	r, err := openInput(fs)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+func runJSON(args []string) error {
+	fs := newFlagSet("json")
+	compact := fs.Bool("compact", false, "print each value on a single line")
+	nonFinite := fs.String("nonfinite", "error", "how to print NaN and infinity: error, null or string")
+	fs.Parse(args)
+
+	var t msgpack.JSONTranscoder
+	switch *nonFinite {
+	case "error":
+		t.NonFinite = msgpack.NonFiniteError
+	case "null":
+		t.NonFinite = msgpack.NonFiniteNull
+	case "string":
+		t.NonFinite = msgpack.NonFiniteString
+	default:
+		return fmt.Errorf("invalid -nonfinite value %q", *nonFinite)
+	}
+
+	r, err := openInput(fs)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w := bufio.NewWriter(os.Stdout)
+	err = writeJSON(w, t, msgpack.NewReaderDecoder(r), *compact)
+	if ferr := w.Flush(); ferr != nil {
+		return ferr
+	}
+	return err
+}
+
+// writeJSON writes each value read by d to w as JSON on its own line.
+func writeJSON(w *bufio.Writer, t msgpack.JSONTranscoder, d *msgpack.Decoder, compact bool) error {
+	var (
+		buf    []byte
+		indent bytes.Buffer
+	)
+	for {
+		var err error
+		buf, err = t.AppendJSON(buf[:0], d)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		out := buf
+		if !compact {
+			indent.Reset()
+			if err := json.Indent(&indent, buf, "", "  "); err != nil {
+				return err
+			}
+			out = indent.Bytes()
+		}
+		if _, err := w.Write(out); err != nil {
+			return err
+		}
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+}
+
+func runHex(args []string) error {
+	fs := newFlagSet("hex")
+	fs.Parse(args)
+	data, err := readInput(fs)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(os.Stdout)
+	err = msgpack.Dump(w, data)
+	if ferr := w.Flush(); ferr != nil {
+		return ferr
+	}
+	return err
+}
+
+func runValidate(args []string) error {
+	fs := newFlagSet("validate")
+	fs.Parse(args)
+	r, err := openInput(fs)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	cr := &countingReader{r: r}
+	d := msgpack.NewReaderDecoder(cr)
+	n := 0
+	for {
+		err := d.Validate()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w (value %d)", err, n)
+		}
+		n++
+	}
+	_, err = fmt.Printf("ok: %d values, %d bytes\n", n, cr.n)
+	return err
+}
+
+func runFromJSON(args []string) error {
+	fs := newFlagSet("fromjson")
+	sortKeys := fs.Bool("sort", false, "sort map keys")
+	compactInts := fs.Bool("compact-ints", false, "encode integers in the fewest bytes")
+	compactFloats := fs.Bool("compact-floats", false, "encode floats without a fraction as integers")
+	floats := fs.Bool("floats", false, "encode all numbers as floats")
+	binKeys := fs.String("bin", "", "comma-separated map keys whose base64 string values are encoded as bin")
+	fs.Parse(args)
+
+	t := msgpack.JSONTranscoder{FloatNumbers: *floats}
+	t.Encoder.SetSortMapKeys(*sortKeys)
+	t.Encoder.UseCompactInts(*compactInts)
+	t.Encoder.UseCompactFloats(*compactFloats)
+	if *binKeys != "" {
+		t.BinKeys = strings.Split(*binKeys, ",")
+	}
+
+	r, err := openInput(fs)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	b, err := t.FromJSONReader(nil, r)
+	if _, werr := os.Stdout.Write(b); werr != nil {
+		return werr
+	}
+	return err
+}
+
+func runSplit(args []string) error {
+	fs := newFlagSet("split")
+	prefix := fs.String("prefix", "msg-", "prefix of the output file names, which may include a directory")
+	fs.Parse(args)
+	r, err := openInput(fs)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	d := msgpack.NewReaderDecoder(r)
+	for i := 0; ; i++ {
+		raw, err := d.DecodeRaw()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w (value %d)", err, i)
+		}
+		name := fmt.Sprintf("%s%04d.msgpack", *prefix, i)
+		if err := os.WriteFile(name, raw, 0o644); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const annotation = "//msgpack:gen"
+
+// codec describes how to encode and decode a field of a known type. The %s
+// verb is replaced with the field selector, e.g. x.Name.
+type codec struct {
+	append string
+	decode string
+}
+
+var basicCodecs = map[string]codec{
+	"string":        {"msgpack.AppendString(dst, %s)", "%s, err = d.DecodeString()"},
+	"[]byte":        {"msgpack.AppendBytes(dst, %s)", "%s, err = d.DecodeBytes()"},
+	"bool":          {"msgpack.AppendBool(dst, %s)", "%s, err = d.DecodeBool()"},
+	"int":           {"msgpack.AppendInt(dst, int64(%s))", "%s, err = d.DecodeInt()"},
+	"int8":          {"msgpack.AppendInt8(dst, %s)", "%s, err = d.DecodeInt8()"},
+	"int16":         {"msgpack.AppendInt16(dst, %s)", "%s, err = d.DecodeInt16()"},
+	"int32":         {"msgpack.AppendInt32(dst, %s)", "%s, err = d.DecodeInt32()"},
+	"int64":         {"msgpack.AppendInt64(dst, %s)", "%s, err = d.DecodeInt64()"},
+	"uint":          {"msgpack.AppendUint(dst, uint64(%s))", "%s, err = d.DecodeUint()"},
+	"uint8":         {"msgpack.AppendUint8(dst, %s)", "%s, err = d.DecodeUint8()"},
+	"byte":          {"msgpack.AppendUint8(dst, %s)", "%s, err = d.DecodeUint8()"},
+	"uint16":        {"msgpack.AppendUint16(dst, %s)", "%s, err = d.DecodeUint16()"},
+	"uint32":        {"msgpack.AppendUint32(dst, %s)", "%s, err = d.DecodeUint32()"},
+	"uint64":        {"msgpack.AppendUint64(dst, %s)", "%s, err = d.DecodeUint64()"},
+	"float32":       {"msgpack.AppendFloat32(dst, %s)", "%s, err = d.DecodeFloat32()"},
+	"float64":       {"msgpack.AppendFloat64(dst, %s)", "%s, err = d.DecodeFloat64()"},
+	"time.Time":     {"msgpack.AppendTime(dst, %s)", "%s, err = d.DecodeTime()"},
+	"time.Duration": {"msgpack.AppendInt64(dst, int64(%s))", "%s, err = d.DecodeDuration()"},
+	"[]string":      {"msgpack.DefaultEncoder.AppendStringSlice(dst, %s)", "err = d.Decode(&%s)"},
+}
+
+var (
+	// Generated types are decoded through Decode, which counts them against
+	// the limits of the Decoder.
+	generatedCodec = codec{"%s.AppendMsgpack(dst)", "err = d.Decode(&%s)"}
+	fallbackCodec  = codec{"msgpack.Append(dst, %s)", "err = d.Decode(&%s)"}
+)
+
+type genField struct {
+	name   string // msgpack map key
+	path   string // Go selector relative to the receiver
+	depth  int
+	tagged bool // name comes from a struct tag
+	typ    ast.Expr
+}
+
+type generator struct {
+	structs   map[string]*ast.StructType // all struct types of the package
+	annotated map[string]bool
+
+	buf         bytes.Buffer
+	useMsgpcode bool
+}
+
+func generate(file, output string) error {
+	f, pkg, err := parsePackage(file)
+	if err != nil {
+		return err
+	}
+
+	g := &generator{
+		structs:   make(map[string]*ast.StructType),
+		annotated: make(map[string]bool),
+	}
+	for _, pf := range pkg {
+		g.collectTypes(pf)
+	}
+
+	var names []string
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if g.annotated[ts.Name.Name] {
+				if ts.TypeParams != nil {
+					return fmt.Errorf("%s: generic type %s is not supported", file, ts.Name.Name)
+				}
+				names = append(names, ts.Name.Name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("%s: no %s struct types found", file, annotation)
+	}
+
+	var body bytes.Buffer
+	for _, name := range names {
+		g.buf.Reset()
+		if err := g.genType(name); err != nil {
+			return fmt.Errorf("%s: %s", file, err)
+		}
+		body.Write(g.buf.Bytes())
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by msgpackgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", f.Name.Name)
+	fmt.Fprintf(&out, "import (\n")
+	fmt.Fprintf(&out, "\t\"github.com/nurlybekovnt/msgpack\"\n")
+	if g.useMsgpcode {
+		fmt.Fprintf(&out, "\t\"github.com/nurlybekovnt/msgpack/msgpcode\"\n")
+	}
+	fmt.Fprintf(&out, ")\n")
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return fmt.Errorf("%s: formatting generated code: %s", file, err)
+	}
+
+	if output == "" {
+		output = strings.TrimSuffix(file, ".go") + "_msgpack.go"
+	}
+	return os.WriteFile(output, src, 0o644)
+}
+
+// parsePackage parses file and the other files of its package, which are
+// needed to resolve annotated and embedded types declared elsewhere.
+func parsePackage(file string) (*ast.File, []*ast.File, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dir := filepath.Dir(file)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pkg := []*ast.File{f}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() ||
+			!strings.HasSuffix(name, ".go") ||
+			strings.HasSuffix(name, "_test.go") ||
+			name == filepath.Base(file) {
+			continue
+		}
+		pf, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, nil, err
+		}
+		if pf.Name.Name == f.Name.Name {
+			pkg = append(pkg, pf)
+		}
+	}
+	return f, pkg, nil
+}
+
+func (g *generator) collectTypes(f *ast.File) {
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			g.structs[ts.Name.Name] = st
+
+			doc := ts.Doc
+			if doc == nil && len(gd.Specs) == 1 {
+				doc = gd.Doc
+			}
+			if hasAnnotation(doc) {
+				g.annotated[ts.Name.Name] = true
+			}
+		}
+	}
+}
+
+func hasAnnotation(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == annotation {
+			return true
+		}
+	}
+	return false
+}
+
+// fields collects the fields of st, inlining embedded structs. Embedded struct
+// pointers are rejected. Conflicting names are resolved by dominantFields.
+func (g *generator) fields(st *ast.StructType, prefix string, depth int, fs []genField) ([]genField, error) {
+	for _, f := range st.Fields.List {
+		var tag string
+		if f.Tag != nil {
+			s, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return nil, err
+			}
+			tag = reflect.StructTag(s).Get("msgpack")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if len(f.Names) == 0 {
+			typ := f.Type
+			if star, ok := typ.(*ast.StarExpr); ok {
+				typ = star.X
+			}
+			if name == "" && typ == f.Type {
+				if ident, ok := typ.(*ast.Ident); ok {
+					if st, ok := g.structs[ident.Name]; ok {
+						var err error
+						fs, err = g.fields(st, prefix+ident.Name+".", depth+1, fs)
+						if err != nil {
+							return nil, err
+						}
+						continue
+					}
+				}
+				if _, ok := typ.(*ast.SelectorExpr); ok {
+					return nil, fmt.Errorf("embedded field %s is not supported", types.ExprString(typ))
+				}
+			}
+			if name == "" && typ != f.Type {
+				// msgpack inlines embedded struct pointers, skipping them when
+				// nil, which the generated code doesn't mirror.
+				isStruct := false
+				if ident, ok := typ.(*ast.Ident); ok {
+					_, isStruct = g.structs[ident.Name]
+				}
+				if _, ok := typ.(*ast.SelectorExpr); ok || isStruct {
+					return nil, fmt.Errorf("embedded field %s is not supported", types.ExprString(f.Type))
+				}
+			}
+
+			typeName := types.ExprString(typ)
+			fieldName := typeName[strings.LastIndex(typeName, ".")+1:]
+			if !ast.IsExported(fieldName) {
+				continue
+			}
+			tagged := name != ""
+			if !tagged {
+				name = fieldName
+			}
+			fs = append(fs, genField{name: name, path: prefix + fieldName, depth: depth, tagged: tagged, typ: f.Type})
+			continue
+		}
+
+		for _, ident := range f.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			fieldName := name
+			if fieldName == "" {
+				fieldName = ident.Name
+			}
+			fs = append(fs, genField{name: fieldName, path: prefix + ident.Name, depth: depth, tagged: name != "", typ: f.Type})
+		}
+	}
+	return fs, nil
+}
+
+// dominantFields mirrors the field layout msgpack uses for reflection: of the
+// fields sharing a name the least nested one wins, then the only tagged one,
+// and otherwise the name is dropped. Fields keep the order of their names'
+// first appearance.
+func dominantFields(all []genField) []genField {
+	var fs []genField
+	seen := make(map[string]bool)
+	for _, f := range all {
+		if seen[f.name] {
+			continue
+		}
+		seen[f.name] = true
+
+		var (
+			dominant genField
+			n        int // fields as good as dominant
+		)
+		for _, ff := range all {
+			switch {
+			case ff.name != f.name:
+			case n == 0 || ff.depth < dominant.depth:
+				dominant, n = ff, 1
+			case ff.depth > dominant.depth:
+			case ff.tagged && !dominant.tagged:
+				dominant, n = ff, 1
+			case ff.tagged == dominant.tagged:
+				n++
+			}
+		}
+		if n == 1 {
+			fs = append(fs, dominant)
+		}
+	}
+	return fs
+}
+
+func (g *generator) genType(name string) error {
+	fs, err := g.fields(g.structs[name], "", 0, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %s", name, err)
+	}
+	fs = dominantFields(fs)
+
+	g.printf("\n// AppendMsgpack implements msgpack.Marshaler.\n")
+	g.printf("func (x %s) AppendMsgpack(dst []byte) []byte {\n", name)
+	g.printf("dst = msgpack.AppendMapLen(dst, %d)\n", len(fs))
+	for _, f := range fs {
+		g.printf("dst = msgpack.AppendString(dst, %q)\n", f.name)
+		g.genAppend(f)
+	}
+	g.printf("return dst\n")
+	g.printf("}\n")
+
+	g.printf("\n// DecodeMsgpack implements msgpack.Unmarshaler.\n")
+	g.printf("func (x *%s) DecodeMsgpack(d *msgpack.Decoder) error {\n", name)
+	g.printf("n, err := d.DecodeMapLen()\n")
+	g.printf("if err != nil {\nreturn err\n}\n")
+	g.printf("if n == -1 {\n*x = %s{}\nreturn nil\n}\n", name)
+	g.printf("for i := 0; i < n; i++ {\n")
+	g.printf("key, err := d.DecodeStringBytes()\n")
+	g.printf("if err != nil {\nreturn err\n}\n")
+	g.printf("switch string(key) {\n")
+	for _, f := range fs {
+		g.printf("case %q:\n", f.name)
+		g.genDecode(f)
+	}
+	g.printf("default:\nerr = d.Skip()\n")
+	g.printf("}\n")
+	g.printf("if err != nil {\nreturn err\n}\n")
+	g.printf("}\n")
+	g.printf("return nil\n")
+	g.printf("}\n")
+	return nil
+}
+
+func (g *generator) genAppend(f genField) {
+	sel := "x." + f.path
+	if _, ok := g.generatedPtr(f.typ); ok {
+		g.printf("if %s == nil {\ndst = msgpack.AppendNil(dst)\n} else {\n", sel)
+		g.printf("dst = "+generatedCodec.append+"\n", sel)
+		g.printf("}\n")
+		return
+	}
+	g.printf("dst = "+g.codec(f.typ).append+"\n", sel)
+}
+
+func (g *generator) genDecode(f genField) {
+	sel := "x." + f.path
+	if elem, ok := g.generatedPtr(f.typ); ok {
+		g.useMsgpcode = true
+		g.printf("var c byte\n")
+		g.printf("if c, err = d.PeekCode(); err == nil {\n")
+		g.printf("if c == msgpcode.Nil {\n%s = nil\nerr = d.DecodeNil()\n} else {\n", sel)
+		g.printf("if %s == nil {\n%s = new(%s)\n}\n", sel, sel, elem)
+		g.printf("err = d.Decode(%s)\n", sel)
+		g.printf("}\n}\n")
+		return
+	}
+	g.printf(g.codec(f.typ).decode+"\n", sel)
+}
+
+func (g *generator) codec(typ ast.Expr) codec {
+	if c, ok := basicCodecs[types.ExprString(typ)]; ok {
+		return c
+	}
+	if ident, ok := typ.(*ast.Ident); ok && g.annotated[ident.Name] {
+		return generatedCodec
+	}
+	return fallbackCodec
+}
+
+// generatedPtr reports whether typ is a pointer to an annotated type.
+func (g *generator) generatedPtr(typ ast.Expr) (string, bool) {
+	star, ok := typ.(*ast.StarExpr)
+	if !ok {
+		return "", false
+	}
+	ident, ok := star.X.(*ast.Ident)
+	if !ok || !g.annotated[ident.Name] {
+		return "", false
+	}
+	return ident.Name, true
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
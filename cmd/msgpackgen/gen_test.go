@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGenerateGolden checks that the checked-in code of the gentest package is
+// what msgpackgen currently generates. Run go generate ./... to update it.
+func TestGenerateGolden(t *testing.T) {
+	const (
+		input  = "internal/gentest/types.go"
+		golden = "internal/gentest/types_msgpack.go"
+	)
+	output := filepath.Join(t.TempDir(), "types_msgpack.go")
+	if err := generate(input, output); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("generated code differs from %s; run go generate ./...", golden)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{
+			"no types",
+			"package p\n\ntype T struct{}\n",
+			"no //msgpack:gen struct types found",
+		},
+		{
+			"generic",
+			"package p\n\n//msgpack:gen\ntype T[E any] struct{ V E }\n",
+			"generic type T is not supported",
+		},
+		{
+			"embedded pointer",
+			"package p\n\ntype E struct{ A int }\n\n//msgpack:gen\ntype T struct{ *E }\n",
+			"T: embedded field *E is not supported",
+		},
+		{
+			"embedded selector",
+			"package p\n\nimport \"time\"\n\n//msgpack:gen\ntype T struct{ time.Time }\n",
+			"T: embedded field time.Time is not supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "p.go")
+			if err := os.WriteFile(file, []byte(tt.src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			err := generate(file, "")
+			if want := file + ": " + tt.err; err == nil || err.Error() != want {
+				t.Fatalf("generate() error = %v, want %s", err, want)
+			}
+		})
+	}
+}
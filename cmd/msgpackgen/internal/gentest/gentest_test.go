@@ -0,0 +1,106 @@
+package gentest
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nurlybekovnt/msgpack"
+)
+
+// plainItem has the fields of Item but not its generated methods, so it is
+// encoded and decoded using reflection.
+type plainItem Item
+
+func testItems() []Item {
+	return []Item{
+		{},
+		{
+			ID:      42,
+			Name:    "item",
+			Tags:    []string{"a", "b"},
+			Data:    []byte{1, 2, 3},
+			Created: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+			TTL:     time.Minute,
+			Score:   1.5,
+			Attrs:   map[string]string{"k": "v"},
+			Meta:    Meta{Version: 3, Name: "meta", ID: 7, Owner: "meta", Note: "meta"},
+			Audit:   Audit{By: "audit", Note: "audit"},
+			Part:    Part{Kind: 1, Size: 10},
+			Parts:   []Part{{Kind: 2}, {Size: -1}},
+			Child:   &Item{ID: 1, Child: &Item{Name: "leaf"}},
+		},
+	}
+}
+
+// utc converts the decoded times of the items, which are in the local time
+// zone, to UTC.
+func utc(item *Item) {
+	for ; item != nil; item = item.Child {
+		item.Created = item.Created.UTC()
+	}
+}
+
+func TestGeneratedMatchesReflection(t *testing.T) {
+	for i, item := range testItems() {
+		got := item.AppendMsgpack(nil)
+		want := msgpack.Append(nil, plainItem(item))
+		if !bytes.Equal(got, want) {
+			t.Errorf("item %d: AppendMsgpack() = %x, reflection = %x", i, got, want)
+		}
+	}
+}
+
+func TestGeneratedRoundTrip(t *testing.T) {
+	for i, item := range testItems() {
+		b := item.AppendMsgpack(nil)
+
+		var got Item
+		if err := msgpack.NewDecoder(b).Decode(&got); err != nil {
+			t.Fatalf("item %d: Decode() error = %v", i, err)
+		}
+		// Fields tagged "-", hidden fields and ambiguous fields aren't encoded.
+		want := item
+		want.Meta.ID = 0
+		want.Meta.Owner = ""
+		want.Meta.Note = ""
+		want.Audit.Note = ""
+		utc(&got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("item %d: Decode() = %+v, want %+v", i, got, want)
+		}
+
+		var plain plainItem
+		if err := msgpack.NewDecoder(b).Decode(&plain); err != nil {
+			t.Fatalf("item %d: reflection Decode() error = %v", i, err)
+		}
+		utc((*Item)(&plain))
+		if !reflect.DeepEqual(Item(plain), want) {
+			t.Errorf("item %d: reflection Decode() = %+v, want %+v", i, plain, want)
+		}
+	}
+}
+
+func TestGeneratedMaxDepth(t *testing.T) {
+	var root Node
+	for i := 0; i < 1000; i++ {
+		root = Node{Value: i, Next: &Node{Value: root.Value, Next: root.Next}}
+	}
+	b := root.AppendMsgpack(nil)
+
+	var n Node
+	d := msgpack.NewDecoder(b)
+	d.SetLimits(msgpack.DecodeLimits{MaxDepth: 10})
+	if err := d.Decode(&n); !errors.Is(err, msgpack.ErrLimitExceeded) {
+		t.Fatalf("Decode() error = %v, want %v", err, msgpack.ErrLimitExceeded)
+	}
+
+	if err := msgpack.NewDecoder(b).Decode(&n); err != nil {
+		t.Fatalf("Decode() without limits error = %v", err)
+	}
+	if !reflect.DeepEqual(n, root) {
+		t.Fatal("Decode() without limits doesn't match the encoded list")
+	}
+}
@@ -0,0 +1,51 @@
+// Package gentest holds types used to test the code generated by msgpackgen.
+package gentest
+
+import "time"
+
+//go:generate go run github.com/nurlybekovnt/msgpack/cmd/msgpackgen
+
+//msgpack:gen
+type Item struct {
+	ID      int64         `msgpack:"id"`
+	Name    string        `msgpack:"name"`
+	Tags    []string      `msgpack:"tags"`
+	Data    []byte        `msgpack:"data"`
+	Created time.Time     `msgpack:"created"`
+	TTL     time.Duration `msgpack:"ttl"`
+	Score   float64
+	Attrs   map[string]string `msgpack:"attrs"`
+	Cache   []byte            `msgpack:"-"`
+	Meta
+	Audit
+	Part  Part
+	Parts []Part
+	Child *Item `msgpack:"child"`
+}
+
+// Meta is inlined into Item.
+type Meta struct {
+	Version uint16
+	Name    string
+	ID      int64  `msgpack:"id"` // shadowed by Item.ID
+	Owner   string // hidden by the tagged Audit.By
+	Note    string // ambiguous with Audit.Note
+}
+
+// Audit is inlined into Item next to Meta.
+type Audit struct {
+	By   string `msgpack:"Owner"`
+	Note string
+}
+
+//msgpack:gen
+type Part struct {
+	Kind uint8 `msgpack:"kind"`
+	Size int
+}
+
+//msgpack:gen
+type Node struct {
+	Value int   `msgpack:"value"`
+	Next  *Node `msgpack:"next"`
+}
@@ -0,0 +1,206 @@
+// Code generated by msgpackgen. DO NOT EDIT.
+
+package gentest
+
+import (
+	"github.com/nurlybekovnt/msgpack"
+	"github.com/nurlybekovnt/msgpack/msgpcode"
+)
+
+// AppendMsgpack implements msgpack.Marshaler.
+func (x Item) AppendMsgpack(dst []byte) []byte {
+	dst = msgpack.AppendMapLen(dst, 14)
+	dst = msgpack.AppendString(dst, "id")
+	dst = msgpack.AppendInt64(dst, x.ID)
+	dst = msgpack.AppendString(dst, "name")
+	dst = msgpack.AppendString(dst, x.Name)
+	dst = msgpack.AppendString(dst, "tags")
+	dst = msgpack.DefaultEncoder.AppendStringSlice(dst, x.Tags)
+	dst = msgpack.AppendString(dst, "data")
+	dst = msgpack.AppendBytes(dst, x.Data)
+	dst = msgpack.AppendString(dst, "created")
+	dst = msgpack.AppendTime(dst, x.Created)
+	dst = msgpack.AppendString(dst, "ttl")
+	dst = msgpack.AppendInt64(dst, int64(x.TTL))
+	dst = msgpack.AppendString(dst, "Score")
+	dst = msgpack.AppendFloat64(dst, x.Score)
+	dst = msgpack.AppendString(dst, "attrs")
+	dst = msgpack.Append(dst, x.Attrs)
+	dst = msgpack.AppendString(dst, "Version")
+	dst = msgpack.AppendUint16(dst, x.Meta.Version)
+	dst = msgpack.AppendString(dst, "Name")
+	dst = msgpack.AppendString(dst, x.Meta.Name)
+	dst = msgpack.AppendString(dst, "Owner")
+	dst = msgpack.AppendString(dst, x.Audit.By)
+	dst = msgpack.AppendString(dst, "Part")
+	dst = x.Part.AppendMsgpack(dst)
+	dst = msgpack.AppendString(dst, "Parts")
+	dst = msgpack.Append(dst, x.Parts)
+	dst = msgpack.AppendString(dst, "child")
+	if x.Child == nil {
+		dst = msgpack.AppendNil(dst)
+	} else {
+		dst = x.Child.AppendMsgpack(dst)
+	}
+	return dst
+}
+
+// DecodeMsgpack implements msgpack.Unmarshaler.
+func (x *Item) DecodeMsgpack(d *msgpack.Decoder) error {
+	n, err := d.DecodeMapLen()
+	if err != nil {
+		return err
+	}
+	if n == -1 {
+		*x = Item{}
+		return nil
+	}
+	for i := 0; i < n; i++ {
+		key, err := d.DecodeStringBytes()
+		if err != nil {
+			return err
+		}
+		switch string(key) {
+		case "id":
+			x.ID, err = d.DecodeInt64()
+		case "name":
+			x.Name, err = d.DecodeString()
+		case "tags":
+			err = d.Decode(&x.Tags)
+		case "data":
+			x.Data, err = d.DecodeBytes()
+		case "created":
+			x.Created, err = d.DecodeTime()
+		case "ttl":
+			x.TTL, err = d.DecodeDuration()
+		case "Score":
+			x.Score, err = d.DecodeFloat64()
+		case "attrs":
+			err = d.Decode(&x.Attrs)
+		case "Version":
+			x.Meta.Version, err = d.DecodeUint16()
+		case "Name":
+			x.Meta.Name, err = d.DecodeString()
+		case "Owner":
+			x.Audit.By, err = d.DecodeString()
+		case "Part":
+			err = d.Decode(&x.Part)
+		case "Parts":
+			err = d.Decode(&x.Parts)
+		case "child":
+			var c byte
+			if c, err = d.PeekCode(); err == nil {
+				if c == msgpcode.Nil {
+					x.Child = nil
+					err = d.DecodeNil()
+				} else {
+					if x.Child == nil {
+						x.Child = new(Item)
+					}
+					err = d.Decode(x.Child)
+				}
+			}
+		default:
+			err = d.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendMsgpack implements msgpack.Marshaler.
+func (x Part) AppendMsgpack(dst []byte) []byte {
+	dst = msgpack.AppendMapLen(dst, 2)
+	dst = msgpack.AppendString(dst, "kind")
+	dst = msgpack.AppendUint8(dst, x.Kind)
+	dst = msgpack.AppendString(dst, "Size")
+	dst = msgpack.AppendInt(dst, int64(x.Size))
+	return dst
+}
+
+// DecodeMsgpack implements msgpack.Unmarshaler.
+func (x *Part) DecodeMsgpack(d *msgpack.Decoder) error {
+	n, err := d.DecodeMapLen()
+	if err != nil {
+		return err
+	}
+	if n == -1 {
+		*x = Part{}
+		return nil
+	}
+	for i := 0; i < n; i++ {
+		key, err := d.DecodeStringBytes()
+		if err != nil {
+			return err
+		}
+		switch string(key) {
+		case "kind":
+			x.Kind, err = d.DecodeUint8()
+		case "Size":
+			x.Size, err = d.DecodeInt()
+		default:
+			err = d.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendMsgpack implements msgpack.Marshaler.
+func (x Node) AppendMsgpack(dst []byte) []byte {
+	dst = msgpack.AppendMapLen(dst, 2)
+	dst = msgpack.AppendString(dst, "value")
+	dst = msgpack.AppendInt(dst, int64(x.Value))
+	dst = msgpack.AppendString(dst, "next")
+	if x.Next == nil {
+		dst = msgpack.AppendNil(dst)
+	} else {
+		dst = x.Next.AppendMsgpack(dst)
+	}
+	return dst
+}
+
+// DecodeMsgpack implements msgpack.Unmarshaler.
+func (x *Node) DecodeMsgpack(d *msgpack.Decoder) error {
+	n, err := d.DecodeMapLen()
+	if err != nil {
+		return err
+	}
+	if n == -1 {
+		*x = Node{}
+		return nil
+	}
+	for i := 0; i < n; i++ {
+		key, err := d.DecodeStringBytes()
+		if err != nil {
+			return err
+		}
+		switch string(key) {
+		case "value":
+			x.Value, err = d.DecodeInt()
+		case "next":
+			var c byte
+			if c, err = d.PeekCode(); err == nil {
+				if c == msgpcode.Nil {
+					x.Next = nil
+					err = d.DecodeNil()
+				} else {
+					if x.Next == nil {
+						x.Next = new(Node)
+					}
+					err = d.Decode(x.Next)
+				}
+			}
+		default:
+			err = d.Skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Command msgpackgen generates msgpack.Marshaler and msgpack.Unmarshaler
+// implementations for struct types, so that they can be encoded and decoded
+// without reflection.
+//
+// Struct types are selected with a //msgpack:gen comment in their doc comment:
+//
+//	//go:generate msgpackgen
+//
+//	//msgpack:gen
+//	type Item struct {
+//		ID    int64  `msgpack:"id"`
+//		Name  string `msgpack:"name"`
+//		Cache []byte `msgpack:"-"`
+//	}
+//
+// For every input file containing annotated types msgpackgen writes a
+// <name>_msgpack.go file next to it. Without arguments the file named by the
+// $GOFILE environment variable, which is set by go generate, is processed.
+//
+// The generated methods produce the same encoding as msgpack.Append with the
+// default Encoder: structs are encoded as maps keyed by field name or
+// `msgpack:"name"` tag. Decoders match keys without allocating and skip
+// unknown keys. Fields of types msgpackgen doesn't know fall back to
+// msgpack.Append and Decoder.Decode. Fields of generated types are decoded
+// through Decoder.Decode too, so that nesting counts against the decode limits.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	output := flag.String("o", "", "output file name; only valid with a single input file")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: msgpackgen [-o output] [file.go ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	files := flag.Args()
+	if len(files) == 0 {
+		if gofile := os.Getenv("GOFILE"); gofile != "" {
+			files = []string{gofile}
+		}
+	}
+	if len(files) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *output != "" && len(files) > 1 {
+		fatalf("-o requires a single input file")
+	}
+
+	for _, file := range files {
+		if err := generate(file, *output); err != nil {
+			fatalf("%s", err)
+		}
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "msgpackgen: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -2,8 +2,8 @@ package msgpack
 
 import (
 	"errors"
-	"fmt"
 	"io"
+	"reflect"
 	"sync"
 	"time"
 
@@ -13,11 +13,14 @@ import (
 const (
 	looseInterfaceDecodingFlag uint32 = 1 << iota
 	unsafeDecodingFlag
+	overflowCheckFlag
+	strictDecodingFlag
 )
 
 const (
 	sliceAllocLimit = 1e4
 	maxMapSize      = 1e6
+	readerBufSize   = 4096
 )
 
 var decPool = sync.Pool{
@@ -40,6 +43,18 @@ type Decoder struct {
 	data  []byte
 	i     int // current reading index
 
+	// r is the source of data for reader-backed decoders. data is then an
+	// internal buffer that is refilled from r.
+	r io.Reader
+	// mark is the index of the first byte that must be kept on refill or -1.
+	mark int
+	// off is the input offset of data[0].
+	off int
+
+	limits    DecodeLimits
+	depth     int // current nesting depth of arrays and maps
+	allocated int // bytes accounted for MaxAllocBytes
+
 	mapDecoder func(*Decoder) (interface{}, error)
 }
 
@@ -47,11 +62,41 @@ type Decoder struct {
 func (d *Decoder) Reset(b []byte) {
 	d.data = b
 	d.i = 0
+	d.r = nil
+	d.off = 0
+	d.depth = 0
+	d.allocated = 0
+}
+
+// ResetReader resets the Decoder to be decoding from r. The internal buffer of
+// a reader-backed Decoder is reused.
+func (d *Decoder) ResetReader(r io.Reader) {
+	if d.r == nil {
+		// data belongs to the caller.
+		d.data = nil
+	}
+	d.data = d.data[:0]
+	d.i = 0
+	d.r = r
+	d.mark = -1
+	d.off = 0
+	d.depth = 0
+	d.allocated = 0
 }
 
 // NewDecoder returns a new Decoder decoding from b.
 func NewDecoder(b []byte) *Decoder { return &Decoder{data: b} }
 
+// NewReaderDecoder returns a new Decoder decoding from r. The Decoder reads
+// from r into an internal buffer as needed and may read more data from r than
+// necessary to decode the next value. It supports all the decoding methods,
+// but strings and byte slices are always copied, see UseUnsafeDecoding.
+func NewReaderDecoder(r io.Reader) *Decoder {
+	d := new(Decoder)
+	d.ResetReader(r)
+	return d
+}
+
 func (d *Decoder) SetMapDecoder(fn func(*Decoder) (interface{}, error)) {
 	d.mapDecoder = fn
 }
@@ -71,7 +116,8 @@ func (d *Decoder) UseLooseInterfaceDecoding(on bool) {
 // faster decoding speed. However, this approach requires careful use as it can
 // lead to memory corruption and memory leaks. When the flag is off, the decoder
 // allocates new memory for strings and byte slices. That is the default
-// behaviour.
+// behaviour. The flag has no effect on decoders created with NewReaderDecoder
+// because their buffer is overwritten on refill.
 func (d *Decoder) UseUnsafeDecoding(on bool) {
 	if on {
 		d.flags |= unsafeDecodingFlag
@@ -80,13 +126,55 @@ func (d *Decoder) UseUnsafeDecoding(on bool) {
 	}
 }
 
+// UseOverflowCheck causes the decoder to check that decoded integers fit into
+// the target type, including values decoded with Decode, instead of silently
+// truncating them. For example, decoding 300 with DecodeInt8 or -1 with
+// DecodeUint64 returns an error wrapping an *OverflowError that carries the
+// decoded value and the target type.
+func (d *Decoder) UseOverflowCheck(on bool) {
+	if on {
+		d.flags |= overflowCheckFlag
+	} else {
+		d.flags &= ^overflowCheckFlag
+	}
+}
+
+// UseStrictDecoding causes the decoder to reject values whose msgpack family
+// doesn't match the target exactly instead of converting them:
+//   - nil is only accepted by nilable targets such as pointers, slices, maps
+//     and interfaces, but not by bools, numbers, strings, structs or arrays,
+//   - integers are not accepted as floats,
+//   - time is only decoded from the timestamp extension, not from strings or
+//     the legacy array format.
+//
+// The returned errors match ErrUnexpectedCode and name the expected and the
+// actual msgpack family.
+func (d *Decoder) UseStrictDecoding(on bool) {
+	if on {
+		d.flags |= strictDecodingFlag
+	} else {
+		d.flags &= ^strictDecodingFlag
+	}
+}
+
+func (d *Decoder) strict() bool {
+	return d.flags&strictDecodingFlag != 0
+}
+
 func (d *Decoder) unsafeDecoding() bool {
-	return d.flags&unsafeDecodingFlag != 0
+	return d.flags&unsafeDecodingFlag != 0 && d.r == nil
 }
 
 // Decode decodes the Msgpack-encoded data and stores the result in the value
 // pointed to by v. If v is nil or not a pointer, Decode returns an error.
 //
+// Values implementing Unmarshaler decode themselves. Strings, byte slices,
+// booleans, integers, floats, time.Time, time.Duration, []string,
+// map[string]string and map[string]interface{} are decoded directly; structs,
+// pointers, slices, arrays and maps of any supported type are decoded using
+// reflection. Structs are decoded from maps whose keys are matched against the
+// field names or `msgpack:"name"` struct tags; unknown keys are skipped.
+//
 // Enabling the UnsafeDecoding flag may improve decoding speed but could lead to
 // potential memory issues as the strings and byte slices reference the
 // underlying decoding byte slice. Exercise caution when modifying decoded data
@@ -185,9 +273,23 @@ func (d *Decoder) Decode(v interface{}) error {
 			*v, err = d.DecodeTime()
 			return err
 		}
+	case Unmarshaler:
+		if rv := reflect.ValueOf(v); rv.Kind() != reflect.Pointer || !rv.IsNil() {
+			return d.decodeUnmarshaler(v)
+		}
 	}
 
-	return fmt.Errorf("msgpack: Decode(unsupported %T)", v)
+	return d.decodeValue(v)
+}
+
+// decodeUnmarshaler calls u.DecodeMsgpack, counting it as a nesting level so
+// that recursive Unmarshalers are bound by MaxDepth.
+func (d *Decoder) decodeUnmarshaler(u Unmarshaler) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+	return u.DecodeMsgpack(d)
 }
 
 func (d *Decoder) DecodeMulti(v ...interface{}) error {
@@ -212,7 +314,7 @@ func (d *Decoder) DecodeNil() error {
 		return err
 	}
 	if c != msgpcode.Nil {
-		return fmt.Errorf("msgpack: invalid code=%x decoding nil", c)
+		return d.codeError(c, "nil")
 	}
 	return nil
 }
@@ -227,6 +329,9 @@ func (d *Decoder) DecodeBool() (bool, error) {
 
 func (d *Decoder) bool(c byte) (bool, error) {
 	if c == msgpcode.Nil {
+		if d.strict() {
+			return false, d.codeError(c, "bool")
+		}
 		return false, nil
 	}
 	if c == msgpcode.False {
@@ -235,7 +340,7 @@ func (d *Decoder) bool(c byte) (bool, error) {
 	if c == msgpcode.True {
 		return true, nil
 	}
-	return false, fmt.Errorf("msgpack: invalid code=%x decoding bool", c)
+	return false, d.codeError(c, "bool")
 }
 
 func (d *Decoder) DecodeDuration() (time.Duration, error) {
@@ -254,6 +359,9 @@ func (d *Decoder) DecodeDuration() (time.Duration, error) {
 //   - float32 and float64,
 //   - string,
 //   - []byte,
+//   - time.Time,
+//   - types registered with RegisterExt,
+//   - RawExt for other extensions,
 //   - slices of any of the above,
 //   - maps of any of the above.
 //
@@ -325,12 +433,12 @@ func (d *Decoder) DecodeInterface() (interface{}, error) {
 			return nil, err
 		}
 		return d.decodeMapDefault()
-		// case msgpcode.FixExt1, msgpcode.FixExt2, msgpcode.FixExt4, msgpcode.FixExt8, msgpcode.FixExt16,
-		// 	msgpcode.Ext8, msgpcode.Ext16, msgpcode.Ext32:
-		// 	return d.decodeInterfaceExt(c)
+	case msgpcode.FixExt1, msgpcode.FixExt2, msgpcode.FixExt4, msgpcode.FixExt8, msgpcode.FixExt16,
+		msgpcode.Ext8, msgpcode.Ext16, msgpcode.Ext32:
+		return d.decodeInterfaceExt(c)
 	}
 
-	return 0, fmt.Errorf("msgpack: unknown code %x decoding interface{}", c)
+	return 0, d.codeError(c, "")
 }
 
 // DecodeInterfaceLoose is like DecodeInterface except that:
@@ -388,12 +496,12 @@ func (d *Decoder) DecodeInterfaceLoose() (interface{}, error) {
 			return nil, err
 		}
 		return d.decodeMapDefault()
-		// case msgpcode.FixExt1, msgpcode.FixExt2, msgpcode.FixExt4, msgpcode.FixExt8, msgpcode.FixExt16,
-		// 	msgpcode.Ext8, msgpcode.Ext16, msgpcode.Ext32:
-		// 	return d.decodeInterfaceExt(c)
+	case msgpcode.FixExt1, msgpcode.FixExt2, msgpcode.FixExt4, msgpcode.FixExt8, msgpcode.FixExt16,
+		msgpcode.Ext8, msgpcode.Ext16, msgpcode.Ext32:
+		return d.decodeInterfaceExt(c)
 	}
 
-	return 0, fmt.Errorf("msgpack: unknown code %x decoding interface{}", c)
+	return 0, d.codeError(c, "")
 }
 
 // Skip skips next value.
@@ -440,7 +548,7 @@ func (d *Decoder) Skip() error {
 		return d.skipExt(c)
 	}
 
-	return fmt.Errorf("msgpack: unknown code %x", c)
+	return d.codeError(c, "")
 }
 
 // PeekCode returns the next MessagePack code without advancing the reader.
@@ -458,7 +566,15 @@ func (d *Decoder) PeekCode() (byte, error) {
 // the returned byte value is undefined.
 func (d *Decoder) readByte() (byte, error) {
 	if d.i >= len(d.data) {
-		return 0, io.EOF
+		if d.r == nil {
+			return 0, io.EOF
+		}
+		if err := d.fill(1); err != nil {
+			if err == io.EOF {
+				return 0, err
+			}
+			return 0, d.wrapError(err)
+		}
 	}
 	b := d.data[d.i]
 	d.i++
@@ -478,11 +594,67 @@ func (d *Decoder) readCode() (byte, error) {
 	return d.readByte()
 }
 
+// readN returns the next n bytes. The returned slice references the input and,
+// for reader-backed decoders, is only valid until the next read.
 func (d *Decoder) readN(n int) ([]byte, error) {
 	if d.i+n > len(d.data) {
-		return nil, io.ErrShortBuffer
+		if d.r == nil {
+			return nil, d.wrapError(io.ErrShortBuffer)
+		}
+		if err := d.fill(n); err != nil {
+			return nil, d.wrapError(err)
+		}
 	}
 	b := d.data[d.i : d.i+n]
 	d.i += n
 	return b, nil
 }
+
+// fill reads from d.r until at least n unread bytes are buffered. Bytes before
+// the current reading index, or before the mark when set, are discarded. fill
+// returns io.EOF only if no bytes were read at all.
+func (d *Decoder) fill(n int) error {
+	keep := d.i
+	if d.mark >= 0 && d.mark < keep {
+		keep = d.mark
+	}
+	if keep > 0 {
+		d.data = d.data[:copy(d.data, d.data[keep:])]
+		d.off += keep
+		d.i -= keep
+		if d.mark >= 0 {
+			d.mark -= keep
+		}
+	}
+
+	need := d.i + n
+	start := len(d.data)
+	for noProgress := 0; len(d.data) < need; {
+		if len(d.data) == cap(d.data) {
+			// Grow gradually so that a bogus length can't force a huge
+			// allocation before the data is actually read.
+			size := min(max(2*cap(d.data), readerBufSize), max(need, readerBufSize))
+			buf := make([]byte, len(d.data), size)
+			copy(buf, d.data)
+			d.data = buf
+		}
+
+		m, err := d.r.Read(d.data[len(d.data):cap(d.data)])
+		d.data = d.data[:len(d.data)+m]
+		if len(d.data) >= need {
+			return nil
+		}
+		if err != nil {
+			if err == io.EOF && len(d.data) > start {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		if m > 0 {
+			noProgress = 0
+		} else if noProgress++; noProgress >= 100 {
+			return io.ErrNoProgress
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package msgpack
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrTruncated is matched by errors.Is for errors returned when the input
+	// ends in the middle of a value.
+	ErrTruncated = errors.New("msgpack: truncated input")
+	// ErrUnexpectedCode is matched by errors.Is for errors returned when the
+	// input contains a msgpack code that can't be decoded into the target.
+	ErrUnexpectedCode = errors.New("msgpack: unexpected code")
+	// ErrLimitExceeded is matched by errors.Is for errors returned when
+	// decoding exceeds one of the DecodeLimits.
+	ErrLimitExceeded = errors.New("msgpack: decode limit exceeded")
+	// ErrTrailingData is matched by errors.Is for errors returned by Valid
+	// when the input continues after the value.
+	ErrTrailingData = errors.New("msgpack: trailing data after value")
+)
+
+// DecodeError describes where decoding failed. Errors caused by the input,
+// such as truncated data, unexpected codes, exceeded limits and integer
+// overflows, are returned as *DecodeError; use errors.As to get the position
+// and errors.Is or errors.As to inspect the underlying error.
+//
+// A clean end of input before the first byte of a top-level value is reported
+// as a bare io.EOF.
+type DecodeError struct {
+	// Offset is the number of input bytes consumed when decoding failed.
+	Offset int
+	// Code and Expected are the code seen and the expected msgpack kind, e.g.
+	// "map" or "integer". They are only set if Err is ErrUnexpectedCode.
+	Code     byte
+	Expected string
+	// Path locates the failed value in nested containers, e.g. items[3].price.
+	// It is empty for top-level values.
+	Path string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	var b strings.Builder
+	b.WriteString("msgpack: ")
+	if e.Err == ErrUnexpectedCode {
+		fmt.Fprintf(&b, "unexpected code=%x (%s)", e.Code, CodeKind(e.Code))
+		if e.Expected != "" {
+			b.WriteString(", expected ")
+			b.WriteString(e.Expected)
+		}
+	} else {
+		b.WriteString(strings.TrimPrefix(e.Err.Error(), "msgpack: "))
+	}
+	b.WriteString(" at offset ")
+	b.WriteString(strconv.Itoa(e.Offset))
+	if e.Path != "" {
+		b.WriteString(" in ")
+		b.WriteString(e.Path)
+	}
+	return b.String()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether e is a truncated input error.
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrTruncated &&
+		(e.Err == io.ErrUnexpectedEOF || e.Err == io.ErrShortBuffer)
+}
+
+// joinPath adds the container key or index seg in front of path.
+func joinPath(seg, path string) string {
+	switch {
+	case path == "":
+		return seg
+	case path[0] == '[':
+		return seg + path
+	default:
+		return seg + "." + path
+	}
+}
+
+// offset returns the number of input bytes consumed so far.
+func (d *Decoder) offset() int {
+	return d.off + d.i
+}
+
+// wrapError returns err as a *DecodeError at the current offset. An io.EOF
+// returned while decoding a nested value means that the input is truncated.
+func (d *Decoder) wrapError(err error) *DecodeError {
+	if e, ok := err.(*DecodeError); ok {
+		return e
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return &DecodeError{Offset: d.offset(), Err: err}
+}
+
+// codeError returns an error for the unexpected code c.
+func (d *Decoder) codeError(c byte, expected string) error {
+	return &DecodeError{
+		Offset:   d.offset(),
+		Code:     c,
+		Expected: expected,
+		Err:      ErrUnexpectedCode,
+	}
+}
+
+// indexError returns err that occurred decoding the i-th array element.
+func (d *Decoder) indexError(err error, i int) error {
+	e := d.wrapError(err)
+	e.Path = joinPath("["+strconv.Itoa(i)+"]", e.Path)
+	return e
+}
+
+// keyError returns err that occurred decoding the map value with key.
+func (d *Decoder) keyError(err error, key string) error {
+	e := d.wrapError(err)
+	e.Path = joinPath(key, e.Path)
+	return e
+}
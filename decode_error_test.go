@@ -0,0 +1,121 @@
+package msgpack_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/nurlybekovnt/msgpack"
+)
+
+type order struct {
+	ID    int64             `msgpack:"id"`
+	Items []orderItem       `msgpack:"items"`
+	Attrs map[string]string `msgpack:"attrs"`
+}
+
+type orderItem struct {
+	Price int `msgpack:"price"`
+}
+
+func TestDecodeError(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		v      interface{}
+		setup  func(d *msgpack.Decoder)
+		target error
+		offset int
+		path   string
+		msg    string
+	}{
+		{
+			name:   "truncated",
+			data:   msgpack.AppendString(nil, "hello")[:3],
+			v:      new(string),
+			target: msgpack.ErrTruncated,
+			offset: 1,
+			msg:    "msgpack: short buffer at offset 1",
+		},
+		{
+			name:   "unexpected code",
+			data:   msgpack.AppendString(nil, "x"),
+			v:      new(int),
+			target: msgpack.ErrUnexpectedCode,
+			offset: 1,
+			msg:    "msgpack: unexpected code=a1 (str), expected integer at offset 1",
+		},
+		{
+			name: "field path",
+			data: msgpack.AppendMapSorted(nil, map[string]interface{}{
+				"id":    1,
+				"items": []interface{}{map[string]interface{}{"price": 1}, map[string]interface{}{"price": "x"}},
+			}),
+			v:      new(order),
+			target: msgpack.ErrUnexpectedCode,
+			offset: 28,
+			path:   "items[1].price",
+			msg:    "msgpack: unexpected code=a1 (str), expected integer at offset 28 in items[1].price",
+		},
+		{
+			name:   "map key path",
+			data:   msgpack.Append(nil, map[string]interface{}{"attrs": map[string]interface{}{"k": 1}}),
+			v:      new(order),
+			target: msgpack.ErrUnexpectedCode,
+			offset: 11,
+			path:   "attrs.k",
+		},
+		{
+			name:   "limit",
+			data:   msgpack.Append(nil, []int{1, 2, 3}),
+			v:      new([]int),
+			setup:  func(d *msgpack.Decoder) { d.SetLimits(msgpack.DecodeLimits{MaxArrayLen: 2}) },
+			target: msgpack.ErrLimitExceeded,
+			offset: 1,
+			msg:    "msgpack: MaxArrayLen 3 exceeds limit 2 at offset 1",
+		},
+		{
+			name:   "overflow",
+			data:   msgpack.Append(nil, []int{1, 300}),
+			v:      new([]int8),
+			setup:  func(d *msgpack.Decoder) { d.UseOverflowCheck(true) },
+			offset: 5,
+			path:   "[1]",
+			msg:    "msgpack: value 300 overflows int8 at offset 5 in [1]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := msgpack.NewDecoder(tt.data)
+			if tt.setup != nil {
+				tt.setup(d)
+			}
+			err := d.Decode(tt.v)
+
+			var de *msgpack.DecodeError
+			if !errors.As(err, &de) {
+				t.Fatalf("Decode() error = %v (%T), want *DecodeError", err, err)
+			}
+			if tt.target != nil && !errors.Is(err, tt.target) {
+				t.Errorf("Decode() error = %v, want %v", err, tt.target)
+			}
+			if de.Offset != tt.offset || de.Path != tt.path {
+				t.Errorf("Decode() error at offset %d in %q, want %d in %q", de.Offset, de.Path, tt.offset, tt.path)
+			}
+			if tt.msg != "" && err.Error() != tt.msg {
+				t.Errorf("Decode() error = %q, want %q", err, tt.msg)
+			}
+		})
+	}
+}
+
+func TestDecodeErrorEOF(t *testing.T) {
+	d := msgpack.NewDecoder(msgpack.AppendInt(nil, 1))
+	var n int
+	if err := d.Decode(&n); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Decode(&n); err != io.EOF {
+		t.Fatalf("Decode() at the end error = %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,96 @@
+package msgpack
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/nurlybekovnt/msgpack/msgpcode"
+)
+
+// LimitError is returned when decoding exceeds one of the DecodeLimits.
+type LimitError struct {
+	Limit string // name of the exceeded DecodeLimits field
+	Value int    // value that exceeded the limit
+	Max   int    // configured limit
+}
+
+func (err *LimitError) Error() string {
+	return fmt.Sprintf("msgpack: %s %d exceeds limit %d", err.Limit, err.Value, err.Max)
+}
+
+func (err *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// DecodeLimits bounds the resources a Decoder may spend on a single input.
+// The limits are enforced by all decoding methods, including Skip. A zero
+// field means no limit.
+type DecodeLimits struct {
+	// MaxDepth is the maximum nesting depth of arrays and maps. Each call of
+	// an Unmarshaler counts as one level.
+	MaxDepth int
+	// MaxArrayLen is the maximum number of elements of an array.
+	MaxArrayLen int
+	// MaxMapLen is the maximum number of key-value pairs of a map.
+	MaxMapLen int
+	// MaxStringLen is the maximum length of a string in bytes.
+	MaxStringLen int
+	// MaxBinLen is the maximum length of binary data in bytes.
+	MaxBinLen int
+	// MaxExtLen is the maximum length of an ext payload in bytes.
+	MaxExtLen int
+	// MaxAllocBytes is the maximum total number of bytes allocated for decoded
+	// strings, byte slices and containers since the last Reset. The accounting
+	// is approximate.
+	MaxAllocBytes int
+}
+
+// SetLimits sets the resource limits of the Decoder.
+func (d *Decoder) SetLimits(limits DecodeLimits) {
+	d.limits = limits
+}
+
+const (
+	ifaceSize  = int(unsafe.Sizeof(interface{}(nil)))
+	stringSize = int(unsafe.Sizeof(""))
+)
+
+// enter must be called before decoding the elements of an array or a map and
+// leave after that.
+func (d *Decoder) enter() error {
+	if d.limits.MaxDepth > 0 && d.depth >= d.limits.MaxDepth {
+		return d.wrapError(&LimitError{Limit: "MaxDepth", Value: d.depth + 1, Max: d.limits.MaxDepth})
+	}
+	d.depth++
+	return nil
+}
+
+func (d *Decoder) leave() {
+	d.depth--
+}
+
+// alloc accounts for n allocated bytes.
+func (d *Decoder) alloc(n int) error {
+	if d.limits.MaxAllocBytes <= 0 {
+		return nil
+	}
+	d.allocated += n
+	if d.allocated > d.limits.MaxAllocBytes {
+		return d.wrapError(&LimitError{Limit: "MaxAllocBytes", Value: d.allocated, Max: d.limits.MaxAllocBytes})
+	}
+	return nil
+}
+
+func (d *Decoder) checkLimit(name string, n, max int) error {
+	if max > 0 && n > max {
+		return d.wrapError(&LimitError{Limit: name, Value: n, Max: max})
+	}
+	return nil
+}
+
+func (d *Decoder) checkBytesLen(c byte, n int) error {
+	if msgpcode.IsBin(c) {
+		return d.checkLimit("MaxBinLen", n, d.limits.MaxBinLen)
+	}
+	return d.checkLimit("MaxStringLen", n, d.limits.MaxStringLen)
+}
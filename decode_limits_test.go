@@ -0,0 +1,80 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/nurlybekovnt/msgpack"
+)
+
+// chain decodes nested arrays of at most one element, one Unmarshaler call
+// per level.
+type chain struct {
+	next *chain
+}
+
+func (c *chain) DecodeMsgpack(d *msgpack.Decoder) error {
+	n, err := d.DecodeArrayLen()
+	if err != nil || n <= 0 {
+		return err
+	}
+	c.next = new(chain)
+	return d.Decode(c.next)
+}
+
+// ints decodes its array with DecodeSliceOf.
+type ints []int
+
+func (s *ints) DecodeMsgpack(d *msgpack.Decoder) error {
+	v, err := msgpack.DecodeSliceOf[int](d)
+	*s = v
+	return err
+}
+
+// counts decodes its map with DecodeMapOf.
+type counts map[string]int
+
+func (m *counts) DecodeMsgpack(d *msgpack.Decoder) error {
+	v, err := msgpack.DecodeMapOf[string, int](d)
+	*m = v
+	return err
+}
+
+func nestedArrays(depth int) []byte {
+	b := bytes.Repeat([]byte{0x91}, depth)
+	return append(b, 0x90)
+}
+
+func TestDecodeLimitsDepth(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		v        func() interface{}
+		maxDepth int
+		exceeded bool
+	}{
+		{"unmarshaler deep", nestedArrays(1000), func() interface{} { return new(chain) }, 10, true},
+		{"unmarshaler shallow", nestedArrays(5), func() interface{} { return new(chain) }, 10, false},
+		{"unmarshaler no limit", nestedArrays(1000), func() interface{} { return new(chain) }, 0, false},
+		{"unmarshaler elem", nestedArrays(1000), func() interface{} { return new([]chain) }, 10, true},
+		{"slice of", msgpack.AppendSlice(msgpack.Encoder{}, nil, []int{1, 2}), func() interface{} { return new(ints) }, 1, true},
+		{"slice of within limit", msgpack.AppendSlice(msgpack.Encoder{}, nil, []int{1, 2}), func() interface{} { return new(ints) }, 2, false},
+		{"map of", msgpack.AppendMapOf(msgpack.Encoder{}, nil, map[string]int{"a": 1}), func() interface{} { return new(counts) }, 1, true},
+		{"map of within limit", msgpack.AppendMapOf(msgpack.Encoder{}, nil, map[string]int{"a": 1}), func() interface{} { return new(counts) }, 2, false},
+		{"reflection", nestedArrays(20), func() interface{} { return new(interface{}) }, 10, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := msgpack.NewDecoder(tt.data)
+			d.SetLimits(msgpack.DecodeLimits{MaxDepth: tt.maxDepth})
+			err := d.Decode(tt.v())
+			if got := errors.Is(err, msgpack.ErrLimitExceeded); got != tt.exceeded {
+				t.Fatalf("Decode() error = %v, want limit exceeded %v", err, tt.exceeded)
+			}
+			if !tt.exceeded && err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package msgpack
 
 import (
+	"fmt"
+
 	"github.com/nurlybekovnt/msgpack/msgpcode"
 )
 
@@ -32,21 +34,28 @@ func (d *Decoder) DecodeMapLen() (int, error) {
 }
 
 func (d *Decoder) mapLen(c byte) (int, error) {
-	if c == msgpcode.Nil {
+	var size int
+	switch {
+	case c == msgpcode.Nil:
 		return -1, nil
+	case c >= msgpcode.FixedMapLow && c <= msgpcode.FixedMapHigh:
+		size = int(c & msgpcode.FixedMapMask)
+	case c == msgpcode.Map16:
+		n, err := d.uint16()
+		if err != nil {
+			return 0, err
+		}
+		size = int(n)
+	case c == msgpcode.Map32:
+		n, err := d.uint32()
+		if err != nil {
+			return 0, err
+		}
+		size = int(n)
+	default:
+		return 0, d.codeError(c, "map")
 	}
-	if c >= msgpcode.FixedMapLow && c <= msgpcode.FixedMapHigh {
-		return int(c & msgpcode.FixedMapMask), nil
-	}
-	if c == msgpcode.Map16 {
-		size, err := d.uint16()
-		return int(size), err
-	}
-	if c == msgpcode.Map32 {
-		size, err := d.uint32()
-		return int(size), err
-	}
-	return 0, unexpectedCodeError{code: c, hint: "map length"}
+	return size, d.checkLimit("MaxMapLen", size, d.limits.MaxMapLen)
 }
 
 func (d *Decoder) decodeMapStringStringPtr(ptr *map[string]string) error {
@@ -58,6 +67,13 @@ func (d *Decoder) decodeMapStringStringPtr(ptr *map[string]string) error {
 		*ptr = nil
 		return nil
 	}
+	if err := d.alloc(size * 2 * stringSize); err != nil {
+		return err
+	}
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
 
 	m := *ptr
 	if m == nil {
@@ -68,11 +84,11 @@ func (d *Decoder) decodeMapStringStringPtr(ptr *map[string]string) error {
 	for i := 0; i < size; i++ {
 		mk, err := d.DecodeString()
 		if err != nil {
-			return err
+			return d.wrapError(err)
 		}
 		mv, err := d.DecodeString()
 		if err != nil {
-			return err
+			return d.keyError(err, mk)
 		}
 		m[mk] = mv
 	}
@@ -105,16 +121,24 @@ func (d *Decoder) DecodeMap() (map[string]interface{}, error) {
 		return nil, nil
 	}
 
+	if err := d.alloc(n * (stringSize + ifaceSize)); err != nil {
+		return nil, err
+	}
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer d.leave()
+
 	m := make(map[string]interface{}, min(n, maxMapSize))
 
 	for i := 0; i < n; i++ {
 		mk, err := d.DecodeString()
 		if err != nil {
-			return nil, err
+			return nil, d.wrapError(err)
 		}
 		mv, err := d.decodeInterfaceCond()
 		if err != nil {
-			return nil, err
+			return nil, d.keyError(err, mk)
 		}
 		m[mk] = mv
 	}
@@ -138,17 +162,25 @@ func (d *Decoder) DecodeUntypedMap() (map[interface{}]interface{}, error) {
 		return nil, nil
 	}
 
+	if err := d.alloc(n * 2 * ifaceSize); err != nil {
+		return nil, err
+	}
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer d.leave()
+
 	m := make(map[interface{}]interface{}, min(n, maxMapSize))
 
 	for i := 0; i < n; i++ {
 		mk, err := d.decodeInterfaceCond()
 		if err != nil {
-			return nil, err
+			return nil, d.wrapError(err)
 		}
 
 		mv, err := d.decodeInterfaceCond()
 		if err != nil {
-			return nil, err
+			return nil, d.keyError(err, fmt.Sprint(mk))
 		}
 
 		m[mk] = mv
@@ -162,12 +194,20 @@ func (d *Decoder) skipMap(c byte) error {
 	if err != nil {
 		return err
 	}
+	if n <= 0 {
+		return nil
+	}
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+
 	for i := 0; i < n; i++ {
 		if err := d.Skip(); err != nil {
-			return err
+			return d.wrapError(err)
 		}
 		if err := d.Skip(); err != nil {
-			return err
+			return d.wrapError(err)
 		}
 	}
 	return nil
@@ -3,19 +3,61 @@ package msgpack
 import (
 	"fmt"
 	"math"
+	"reflect"
 
 	"github.com/nurlybekovnt/msgpack/msgpcode"
 )
 
+var (
+	intType    = reflect.TypeOf(int(0))
+	int8Type   = reflect.TypeOf(int8(0))
+	int16Type  = reflect.TypeOf(int16(0))
+	int32Type  = reflect.TypeOf(int32(0))
+	int64Type  = reflect.TypeOf(int64(0))
+	uintType   = reflect.TypeOf(uint(0))
+	uint8Type  = reflect.TypeOf(uint8(0))
+	uint16Type = reflect.TypeOf(uint16(0))
+	uint32Type = reflect.TypeOf(uint32(0))
+	uint64Type = reflect.TypeOf(uint64(0))
+)
+
+// OverflowError is returned when overflow checks are enabled with
+// UseOverflowCheck and a decoded number doesn't fit into the target type.
+type OverflowError struct {
+	Value interface{}  // decoded value, int64 or uint64
+	Type  reflect.Type // target type
+}
+
+func (err *OverflowError) Error() string {
+	return fmt.Sprintf("msgpack: value %v overflows %s", err.Value, err.Type)
+}
+
+// skipN skips the next n bytes. Reader-backed decoders discard them in chunks
+// instead of buffering them, unless they must be kept for DecodeRaw.
 func (d *Decoder) skipN(n int) error {
-	_, err := d.readN(n)
-	return err
+	if d.r == nil || d.mark >= 0 {
+		_, err := d.readN(n)
+		return err
+	}
+	for {
+		m := min(n, len(d.data)-d.i)
+		d.i += m
+		n -= m
+		if n == 0 {
+			return nil
+		}
+		if err := d.fill(min(n, readerBufSize)); err != nil {
+			// The skipped bytes are gone, report the end of the input.
+			d.i = len(d.data)
+			return d.wrapError(err)
+		}
+	}
 }
 
 func (d *Decoder) uint8() (uint8, error) {
 	c, err := d.readCode()
 	if err != nil {
-		return 0, err
+		return 0, d.wrapError(err)
 	}
 	return c, nil
 }
@@ -79,15 +121,54 @@ func (d *Decoder) int64() (int64, error) {
 // DecodeUint64 decodes msgpack int8/16/32/64 and uint8/16/32/64
 // into Go uint64.
 func (d *Decoder) DecodeUint64() (uint64, error) {
+	return d.decodeUint(math.MaxUint64, uint64Type)
+}
+
+// decodeUint decodes an unsigned integer of type typ whose maximum value is
+// max. The range is only checked if overflow checks are enabled.
+func (d *Decoder) decodeUint(max uint64, typ reflect.Type) (uint64, error) {
 	c, err := d.readCode()
 	if err != nil {
 		return 0, err
 	}
-	return d.uint(c)
+	if d.flags&overflowCheckFlag == 0 {
+		return d.uint(c)
+	}
+
+	if isSignedCode(c) {
+		n, err := d.int(c)
+		if err != nil {
+			return 0, err
+		}
+		if n < 0 || uint64(n) > max {
+			return 0, d.wrapError(&OverflowError{Value: n, Type: typ})
+		}
+		return uint64(n), nil
+	}
+
+	n, err := d.uint(c)
+	if err != nil {
+		return 0, err
+	}
+	if n > max {
+		return 0, d.wrapError(&OverflowError{Value: n, Type: typ})
+	}
+	return n, nil
+}
+
+func isSignedCode(c byte) bool {
+	return c >= msgpcode.NegFixedNumLow ||
+		c == msgpcode.Int8 ||
+		c == msgpcode.Int16 ||
+		c == msgpcode.Int32 ||
+		c == msgpcode.Int64
 }
 
 func (d *Decoder) uint(c byte) (uint64, error) {
 	if c == msgpcode.Nil {
+		if d.strict() {
+			return 0, d.codeError(c, "integer")
+		}
 		return 0, nil
 	}
 	if msgpcode.IsFixedNum(c) {
@@ -115,21 +196,52 @@ func (d *Decoder) uint(c byte) (uint64, error) {
 	case msgpcode.Uint64, msgpcode.Int64:
 		return d.uint64()
 	}
-	return 0, fmt.Errorf("msgpack: invalid code=%x decoding uint64", c)
+	return 0, d.codeError(c, "integer")
 }
 
 // DecodeInt64 decodes msgpack int8/16/32/64 and uint8/16/32/64
 // into Go int64.
 func (d *Decoder) DecodeInt64() (int64, error) {
+	return d.decodeInt(math.MinInt64, math.MaxInt64, int64Type)
+}
+
+// decodeInt decodes a signed integer of type typ whose range is [min, max].
+// The range is only checked if overflow checks are enabled.
+func (d *Decoder) decodeInt(min, max int64, typ reflect.Type) (int64, error) {
 	c, err := d.readCode()
 	if err != nil {
 		return 0, err
 	}
-	return d.int(c)
+	if d.flags&overflowCheckFlag == 0 {
+		return d.int(c)
+	}
+
+	if c == msgpcode.Uint64 {
+		n, err := d.uint64()
+		if err != nil {
+			return 0, err
+		}
+		if n > uint64(max) {
+			return 0, d.wrapError(&OverflowError{Value: n, Type: typ})
+		}
+		return int64(n), nil
+	}
+
+	n, err := d.int(c)
+	if err != nil {
+		return 0, err
+	}
+	if n < min || n > max {
+		return 0, d.wrapError(&OverflowError{Value: n, Type: typ})
+	}
+	return n, nil
 }
 
 func (d *Decoder) int(c byte) (int64, error) {
 	if c == msgpcode.Nil {
+		if d.strict() {
+			return 0, d.codeError(c, "integer")
+		}
 		return 0, nil
 	}
 	if msgpcode.IsFixedNum(c) {
@@ -158,7 +270,7 @@ func (d *Decoder) int(c byte) (int64, error) {
 		n, err := d.uint64()
 		return int64(n), err
 	}
-	return 0, fmt.Errorf("msgpack: invalid code=%x decoding int64", c)
+	return 0, d.codeError(c, "integer")
 }
 
 func (d *Decoder) DecodeFloat32() (float32, error) {
@@ -177,10 +289,13 @@ func (d *Decoder) float32(c byte) (float32, error) {
 		}
 		return math.Float32frombits(n), nil
 	}
+	if d.strict() {
+		return 0, d.codeError(c, "float")
+	}
 
 	n, err := d.int(c)
 	if err != nil {
-		return 0, fmt.Errorf("msgpack: invalid code=%x decoding float32", c)
+		return 0, d.codeError(c, "float")
 	}
 	return float32(n), nil
 }
@@ -209,50 +324,53 @@ func (d *Decoder) float64(c byte) (float64, error) {
 		}
 		return math.Float64frombits(n), nil
 	}
+	if d.strict() {
+		return 0, d.codeError(c, "float")
+	}
 
 	n, err := d.int(c)
 	if err != nil {
-		return 0, fmt.Errorf("msgpack: invalid code=%x decoding float32", c)
+		return 0, d.codeError(c, "float")
 	}
 	return float64(n), nil
 }
 
 func (d *Decoder) DecodeUint() (uint, error) {
-	n, err := d.DecodeUint64()
+	n, err := d.decodeUint(math.MaxUint, uintType)
 	return uint(n), err
 }
 
 func (d *Decoder) DecodeUint8() (uint8, error) {
-	n, err := d.DecodeUint64()
+	n, err := d.decodeUint(math.MaxUint8, uint8Type)
 	return uint8(n), err
 }
 
 func (d *Decoder) DecodeUint16() (uint16, error) {
-	n, err := d.DecodeUint64()
+	n, err := d.decodeUint(math.MaxUint16, uint16Type)
 	return uint16(n), err
 }
 
 func (d *Decoder) DecodeUint32() (uint32, error) {
-	n, err := d.DecodeUint64()
+	n, err := d.decodeUint(math.MaxUint32, uint32Type)
 	return uint32(n), err
 }
 
 func (d *Decoder) DecodeInt() (int, error) {
-	n, err := d.DecodeInt64()
+	n, err := d.decodeInt(math.MinInt, math.MaxInt, intType)
 	return int(n), err
 }
 
 func (d *Decoder) DecodeInt8() (int8, error) {
-	n, err := d.DecodeInt64()
+	n, err := d.decodeInt(math.MinInt8, math.MaxInt8, int8Type)
 	return int8(n), err
 }
 
 func (d *Decoder) DecodeInt16() (int16, error) {
-	n, err := d.DecodeInt64()
+	n, err := d.decodeInt(math.MinInt16, math.MaxInt16, int16Type)
 	return int16(n), err
 }
 
 func (d *Decoder) DecodeInt32() (int32, error) {
-	n, err := d.DecodeInt64()
+	n, err := d.decodeInt(math.MinInt32, math.MaxInt32, int32Type)
 	return int32(n), err
 }
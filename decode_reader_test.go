@@ -0,0 +1,135 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReaderDecoder(t *testing.T) {
+	values := []interface{}{
+		nil,
+		true,
+		int64(-1 << 40),
+		uint64(1 << 63),
+		1.5,
+		"short",
+		string(bytes.Repeat([]byte("x"), 3*readerBufSize)),
+		bytes.Repeat([]byte{0xab}, 2*readerBufSize+1),
+		[]interface{}{int8(1), "two", []interface{}{}},
+		map[string]interface{}{"a": int8(1), "b": "c"},
+	}
+	var data []byte
+	for _, v := range values {
+		data = Append(data, v)
+	}
+
+	readers := []struct {
+		name string
+		r    func() io.Reader
+	}{
+		{"bytes", func() io.Reader { return bytes.NewReader(data) }},
+		{"one byte", func() io.Reader { return iotest.OneByteReader(bytes.NewReader(data)) }},
+		{"half", func() io.Reader { return iotest.HalfReader(bytes.NewReader(data)) }},
+		{"data err", func() io.Reader { return iotest.DataErrReader(bytes.NewReader(data)) }},
+	}
+	for _, rt := range readers {
+		t.Run(rt.name+"/decode", func(t *testing.T) {
+			d := NewReaderDecoder(rt.r())
+			for i, want := range values {
+				got, err := d.DecodeInterface()
+				if err != nil {
+					t.Fatalf("value %d: DecodeInterface() error = %v", i, err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("value %d: DecodeInterface() = %v, want %v", i, got, want)
+				}
+			}
+			if _, err := d.DecodeInterface(); err != io.EOF {
+				t.Fatalf("DecodeInterface() at the end error = %v, want io.EOF", err)
+			}
+		})
+		t.Run(rt.name+"/raw", func(t *testing.T) {
+			d := NewReaderDecoder(rt.r())
+			var got []byte
+			for i := range values {
+				raw, err := d.DecodeRaw()
+				if err != nil {
+					t.Fatalf("value %d: DecodeRaw() error = %v", i, err)
+				}
+				got = append(got, raw...)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatal("DecodeRaw() values don't add up to the input")
+			}
+		})
+		t.Run(rt.name+"/skip", func(t *testing.T) {
+			d := NewReaderDecoder(rt.r())
+			for i := range values {
+				if err := d.Skip(); err != nil {
+					t.Fatalf("value %d: Skip() error = %v", i, err)
+				}
+			}
+			if err := d.Skip(); err != io.EOF {
+				t.Fatalf("Skip() at the end error = %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestReaderDecoderSkipBuffer(t *testing.T) {
+	const size = 1 << 20
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"bin", AppendBytes(nil, make([]byte, size))},
+		{"str", AppendString(nil, string(make([]byte, size)))},
+		{"ext", append(AppendExtHeader(nil, 1, size), make([]byte, size)...)},
+		{"array", Append(nil, []interface{}{make([]byte, size), "x"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := append(tt.data, AppendString(nil, "next")...)
+			d := NewReaderDecoder(bytes.NewReader(data))
+			if err := d.Skip(); err != nil {
+				t.Fatalf("Skip() error = %v", err)
+			}
+			if c := cap(d.data); c > 2*readerBufSize {
+				t.Errorf("Skip() grew the buffer to %d bytes", c)
+			}
+			if s, err := d.DecodeString(); err != nil || s != "next" {
+				t.Fatalf("DecodeString() after Skip() = %q, %v", s, err)
+			}
+		})
+	}
+}
+
+func TestReaderDecoderTruncated(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		offset int
+	}{
+		{"header", []byte{0xc5, 0x10}, 1},
+		{"bin", append(AppendBytesLen(nil, 3*readerBufSize), make([]byte, readerBufSize+1)...), 3 + readerBufSize + 1},
+		{"str", []byte{0xa3, 'a'}, 2},
+		{"array", []byte{0x92, 0x01}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewReaderDecoder(bytes.NewReader(tt.data))
+			err := d.Skip()
+			if !errors.Is(err, ErrTruncated) {
+				t.Fatalf("Skip() error = %v, want %v", err, ErrTruncated)
+			}
+			var de *DecodeError
+			if !errors.As(err, &de) || de.Offset != tt.offset {
+				t.Fatalf("Skip() error = %v, want offset %d", err, tt.offset)
+			}
+		})
+	}
+}
@@ -1,8 +1,6 @@
 package msgpack
 
 import (
-	"fmt"
-
 	"github.com/nurlybekovnt/msgpack/msgpcode"
 )
 
@@ -16,20 +14,28 @@ func (d *Decoder) DecodeArrayLen() (int, error) {
 }
 
 func (d *Decoder) arrayLen(c byte) (int, error) {
-	if c == msgpcode.Nil {
+	var size int
+	switch {
+	case c == msgpcode.Nil:
 		return -1, nil
-	} else if c >= msgpcode.FixedArrayLow && c <= msgpcode.FixedArrayHigh {
-		return int(c & msgpcode.FixedArrayMask), nil
-	}
-	switch c {
-	case msgpcode.Array16:
+	case c >= msgpcode.FixedArrayLow && c <= msgpcode.FixedArrayHigh:
+		size = int(c & msgpcode.FixedArrayMask)
+	case c == msgpcode.Array16:
 		n, err := d.uint16()
-		return int(n), err
-	case msgpcode.Array32:
+		if err != nil {
+			return 0, err
+		}
+		size = int(n)
+	case c == msgpcode.Array32:
 		n, err := d.uint32()
-		return int(n), err
+		if err != nil {
+			return 0, err
+		}
+		size = int(n)
+	default:
+		return 0, d.codeError(c, "array")
 	}
-	return 0, fmt.Errorf("msgpack: invalid code=%x decoding array length", c)
+	return size, d.checkLimit("MaxArrayLen", size, d.limits.MaxArrayLen)
 }
 
 func (d *Decoder) decodeStringSlicePtr(ptr *[]string) error {
@@ -40,12 +46,19 @@ func (d *Decoder) decodeStringSlicePtr(ptr *[]string) error {
 	if n == -1 {
 		return nil
 	}
+	if err := d.alloc(n * stringSize); err != nil {
+		return err
+	}
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
 
 	ss := makeStrings(*ptr, n)
 	for i := 0; i < n; i++ {
 		s, err := d.DecodeString()
 		if err != nil {
-			return err
+			return d.indexError(err, i)
 		}
 		ss = append(ss, s)
 	}
@@ -94,12 +107,19 @@ func (d *Decoder) decodeSlice(c byte) ([]interface{}, error) {
 	if n == -1 {
 		return nil, nil
 	}
+	if err := d.alloc(n * ifaceSize); err != nil {
+		return nil, err
+	}
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer d.leave()
 
 	s := make([]interface{}, 0, min(n, sliceAllocLimit))
 	for i := 0; i < n; i++ {
 		v, err := d.decodeInterfaceCond()
 		if err != nil {
-			return nil, err
+			return nil, d.indexError(err, i)
 		}
 		s = append(s, v)
 	}
@@ -112,10 +132,17 @@ func (d *Decoder) skipSlice(c byte) error {
 	if err != nil {
 		return err
 	}
+	if n <= 0 {
+		return nil
+	}
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
 
 	for i := 0; i < n; i++ {
 		if err := d.Skip(); err != nil {
-			return err
+			return d.indexError(err, i)
 		}
 	}
 
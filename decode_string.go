@@ -1,8 +1,6 @@
 package msgpack
 
 import (
-	"fmt"
-
 	"github.com/nurlybekovnt/msgpack/msgpcode"
 )
 
@@ -11,23 +9,32 @@ func (d *Decoder) bytesLen(c byte) (int, error) {
 		return -1, nil
 	}
 
-	if msgpcode.IsFixedString(c) {
-		return int(c & msgpcode.FixedStrMask), nil
-	}
-
-	switch c {
-	case msgpcode.Str8, msgpcode.Bin8:
+	var size int
+	switch {
+	case msgpcode.IsFixedString(c):
+		size = int(c & msgpcode.FixedStrMask)
+	case c == msgpcode.Str8, c == msgpcode.Bin8:
 		n, err := d.uint8()
-		return int(n), err
-	case msgpcode.Str16, msgpcode.Bin16:
+		if err != nil {
+			return 0, err
+		}
+		size = int(n)
+	case c == msgpcode.Str16, c == msgpcode.Bin16:
 		n, err := d.uint16()
-		return int(n), err
-	case msgpcode.Str32, msgpcode.Bin32:
+		if err != nil {
+			return 0, err
+		}
+		size = int(n)
+	case c == msgpcode.Str32, c == msgpcode.Bin32:
 		n, err := d.uint32()
-		return int(n), err
-	}
-
-	return 0, fmt.Errorf("msgpack: invalid code=%x decoding string/bytes length", c)
+		if err != nil {
+			return 0, err
+		}
+		size = int(n)
+	default:
+		return 0, d.codeError(c, "str or bin")
+	}
+	return size, d.checkBytesLen(c, size)
 }
 
 func (d *Decoder) DecodeString() (string, error) {
@@ -43,9 +50,43 @@ func (d *Decoder) string(c byte) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if n == -1 && d.strict() {
+		return "", d.codeError(c, "str")
+	}
 	return d.stringWithLen(n)
 }
 
+// DecodeStringBytes decodes a string and returns its bytes without copying
+// them. The returned slice references the decoder input and is only valid until
+// the next read. It is meant for matching map keys without allocating, e.g.
+// with switch string(key).
+func (d *Decoder) DecodeStringBytes() ([]byte, error) {
+	c, err := d.readCode()
+	if err != nil {
+		return nil, err
+	}
+	n, err := d.bytesLen(c)
+	if err != nil {
+		return nil, err
+	}
+	if n == -1 && d.strict() {
+		return nil, d.codeError(c, "str")
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+	return d.readN(n)
+}
+
+// bytesNoCopy returns the string or binary data with code c without copying it.
+func (d *Decoder) bytesNoCopy(c byte) ([]byte, error) {
+	n, err := d.bytesLen(c)
+	if err != nil || n <= 0 {
+		return nil, err
+	}
+	return d.readN(n)
+}
+
 func (d *Decoder) stringWithLen(n int) (string, error) {
 	if n <= 0 {
 		return "", nil
@@ -59,6 +100,9 @@ func (d *Decoder) stringWithLen(n int) (string, error) {
 	if d.unsafeDecoding() {
 		return bytesToString(b), nil
 	} else {
+		if err := d.alloc(n); err != nil {
+			return "", err
+		}
 		return string(b), nil
 	}
 }
@@ -96,6 +140,9 @@ func (d *Decoder) bytes(c byte) ([]byte, error) {
 	if d.unsafeDecoding() {
 		return b, nil
 	} else {
+		if err := d.alloc(n); err != nil {
+			return nil, err
+		}
 		bb := make([]byte, len(b))
 		copy(bb, b)
 		return bb, nil
@@ -128,9 +175,10 @@ func (d *Decoder) bytesPtr(c byte, ptr *[]byte) error {
 	if d.unsafeDecoding() {
 		*ptr = b
 	} else {
-		bb := makeBytes(*ptr, len(b))
-		copy(bb, b)
-		*ptr = bb
+		if err := d.alloc(n); err != nil {
+			return err
+		}
+		*ptr = append(makeBytes(*ptr, len(b)), b...)
 	}
 
 	return nil
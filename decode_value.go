@@ -0,0 +1,531 @@
+package msgpack
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/nurlybekovnt/msgpack/msgpcode"
+)
+
+type decoderFunc func(d *Decoder, v reflect.Value) error
+
+var decoders sync.Map // map[reflect.Type]decoderFunc
+
+var (
+	bytesType       = reflect.TypeOf((*[]byte)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+)
+
+func (d *Decoder) decodeValue(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer {
+		return fmt.Errorf("msgpack: Decode(non-pointer %T)", v)
+	}
+	if rv.IsNil() {
+		return fmt.Errorf("msgpack: Decode(nil %T)", v)
+	}
+	return getDecoder(rv.Type().Elem())(d, rv.Elem())
+}
+
+// getDecoder returns the cached decoder for typ. Recursive types are resolved
+// with an indirect decoder that waits until the real one is built.
+func getDecoder(typ reflect.Type) decoderFunc {
+	if fn, ok := decoders.Load(typ); ok {
+		return fn.(decoderFunc)
+	}
+
+	var (
+		wg sync.WaitGroup
+		fn decoderFunc
+	)
+	wg.Add(1)
+	fi, loaded := decoders.LoadOrStore(typ, decoderFunc(func(d *Decoder, v reflect.Value) error {
+		wg.Wait()
+		return fn(d, v)
+	}))
+	if loaded {
+		return fi.(decoderFunc)
+	}
+
+	fn = newDecoder(typ)
+	wg.Done()
+	decoders.Store(typ, fn)
+	return fn
+}
+
+func newDecoder(typ reflect.Type) decoderFunc {
+	if info := getExtByType(typ); info != nil {
+		return newExtDecoder(info)
+	}
+	if reflect.PointerTo(typ).Implements(unmarshalerType) {
+		return decodeUnmarshalerValue
+	}
+	if typ == timeType {
+		return decodeTimeValue
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return decodeBoolValue
+	case reflect.Int:
+		return decodeIntValue
+	case reflect.Int8:
+		return decodeInt8Value
+	case reflect.Int16:
+		return decodeInt16Value
+	case reflect.Int32:
+		return decodeInt32Value
+	case reflect.Int64:
+		return decodeInt64Value
+	case reflect.Uint:
+		return decodeUintValue
+	case reflect.Uint8:
+		return decodeUint8Value
+	case reflect.Uint16:
+		return decodeUint16Value
+	case reflect.Uint32:
+		return decodeUint32Value
+	case reflect.Uint64:
+		return decodeUint64Value
+	case reflect.Float32:
+		return decodeFloat32Value
+	case reflect.Float64:
+		return decodeFloat64Value
+	case reflect.String:
+		return decodeStringValue
+	case reflect.Slice:
+		if typ == bytesType {
+			return decodeBytesPtrValue
+		}
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return decodeBytesValue
+		}
+		return newSliceDecoder(typ)
+	case reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return decodeByteArrayValue
+		}
+		return newArrayDecoder(typ)
+	case reflect.Map:
+		return newMapDecoder(typ)
+	case reflect.Struct:
+		return newStructDecoder(typ)
+	case reflect.Pointer:
+		return newPtrDecoder(typ)
+	case reflect.Interface:
+		return decodeInterfaceValue
+	}
+	return newUnsupportedTypeDecoder(typ)
+}
+
+func decodeUnmarshalerValue(d *Decoder, v reflect.Value) error {
+	return d.decodeUnmarshaler(v.Addr().Interface().(Unmarshaler))
+}
+
+func decodeTimeValue(d *Decoder, v reflect.Value) error {
+	return d.Decode(v.Addr().Interface().(*time.Time))
+}
+
+func decodeBoolValue(d *Decoder, v reflect.Value) error {
+	b, err := d.DecodeBool()
+	if err != nil {
+		return err
+	}
+	v.SetBool(b)
+	return nil
+}
+
+func decodeIntValue(d *Decoder, v reflect.Value) error {
+	n, err := d.DecodeInt()
+	if err != nil {
+		return withOverflowType(err, v.Type())
+	}
+	v.SetInt(int64(n))
+	return nil
+}
+
+func decodeInt8Value(d *Decoder, v reflect.Value) error {
+	n, err := d.DecodeInt8()
+	if err != nil {
+		return withOverflowType(err, v.Type())
+	}
+	v.SetInt(int64(n))
+	return nil
+}
+
+func decodeInt16Value(d *Decoder, v reflect.Value) error {
+	n, err := d.DecodeInt16()
+	if err != nil {
+		return withOverflowType(err, v.Type())
+	}
+	v.SetInt(int64(n))
+	return nil
+}
+
+func decodeInt32Value(d *Decoder, v reflect.Value) error {
+	n, err := d.DecodeInt32()
+	if err != nil {
+		return withOverflowType(err, v.Type())
+	}
+	v.SetInt(int64(n))
+	return nil
+}
+
+func decodeInt64Value(d *Decoder, v reflect.Value) error {
+	n, err := d.DecodeInt64()
+	if err != nil {
+		return withOverflowType(err, v.Type())
+	}
+	v.SetInt(n)
+	return nil
+}
+
+func decodeUintValue(d *Decoder, v reflect.Value) error {
+	n, err := d.DecodeUint()
+	if err != nil {
+		return withOverflowType(err, v.Type())
+	}
+	v.SetUint(uint64(n))
+	return nil
+}
+
+func decodeUint8Value(d *Decoder, v reflect.Value) error {
+	n, err := d.DecodeUint8()
+	if err != nil {
+		return withOverflowType(err, v.Type())
+	}
+	v.SetUint(uint64(n))
+	return nil
+}
+
+func decodeUint16Value(d *Decoder, v reflect.Value) error {
+	n, err := d.DecodeUint16()
+	if err != nil {
+		return withOverflowType(err, v.Type())
+	}
+	v.SetUint(uint64(n))
+	return nil
+}
+
+func decodeUint32Value(d *Decoder, v reflect.Value) error {
+	n, err := d.DecodeUint32()
+	if err != nil {
+		return withOverflowType(err, v.Type())
+	}
+	v.SetUint(uint64(n))
+	return nil
+}
+
+func decodeUint64Value(d *Decoder, v reflect.Value) error {
+	n, err := d.DecodeUint64()
+	if err != nil {
+		return withOverflowType(err, v.Type())
+	}
+	v.SetUint(n)
+	return nil
+}
+
+// nilable reports whether msgpack nil can be decoded into values of typ in
+// strict decoding mode.
+func nilable(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// withOverflowType reports overflows of named integer types with the actual
+// target type.
+func withOverflowType(err error, typ reflect.Type) error {
+	var oe *OverflowError
+	if errors.As(err, &oe) {
+		oe.Type = typ
+	}
+	return err
+}
+
+func decodeFloat32Value(d *Decoder, v reflect.Value) error {
+	n, err := d.DecodeFloat32()
+	if err != nil {
+		return err
+	}
+	v.SetFloat(float64(n))
+	return nil
+}
+
+func decodeFloat64Value(d *Decoder, v reflect.Value) error {
+	n, err := d.DecodeFloat64()
+	if err != nil {
+		return err
+	}
+	v.SetFloat(n)
+	return nil
+}
+
+func decodeStringValue(d *Decoder, v reflect.Value) error {
+	s, err := d.DecodeString()
+	if err != nil {
+		return err
+	}
+	v.SetString(s)
+	return nil
+}
+
+func decodeBytesPtrValue(d *Decoder, v reflect.Value) error {
+	return d.decodeBytesPtr(v.Addr().Interface().(*[]byte))
+}
+
+func decodeBytesValue(d *Decoder, v reflect.Value) error {
+	b, err := d.DecodeBytes()
+	if err != nil {
+		return err
+	}
+	v.SetBytes(b)
+	return nil
+}
+
+func decodeByteArrayValue(d *Decoder, v reflect.Value) error {
+	c, err := d.readCode()
+	if err != nil {
+		return err
+	}
+	n, err := d.bytesLen(c)
+	if err != nil {
+		return err
+	}
+	if n == -1 {
+		if d.strict() {
+			return d.codeError(c, "bin")
+		}
+		v.SetZero()
+		return nil
+	}
+
+	b, err := d.readN(n)
+	if err != nil {
+		return err
+	}
+	clear(v.Bytes()[copy(v.Bytes(), b):])
+	return nil
+}
+
+func decodeInterfaceValue(d *Decoder, v reflect.Value) error {
+	if !v.IsNil() {
+		if elem := v.Elem(); elem.Kind() == reflect.Pointer && !elem.IsNil() {
+			return getDecoder(elem.Type())(d, elem)
+		}
+	}
+	if v.NumMethod() != 0 {
+		return fmt.Errorf("msgpack: Decode(unsupported %s)", v.Type())
+	}
+
+	iface, err := d.decodeInterfaceCond()
+	if err != nil {
+		return err
+	}
+	if iface == nil {
+		v.SetZero()
+	} else {
+		v.Set(reflect.ValueOf(iface))
+	}
+	return nil
+}
+
+func newSliceDecoder(typ reflect.Type) decoderFunc {
+	elemDec := getDecoder(typ.Elem())
+	return func(d *Decoder, v reflect.Value) error {
+		n, err := d.DecodeArrayLen()
+		if err != nil {
+			return err
+		}
+		if n == -1 {
+			v.SetZero()
+			return nil
+		}
+		if err := d.alloc(n * int(typ.Elem().Size())); err != nil {
+			return err
+		}
+		if err := d.enter(); err != nil {
+			return err
+		}
+		defer d.leave()
+
+		if v.IsNil() {
+			v.Set(reflect.MakeSlice(typ, 0, min(n, sliceAllocLimit)))
+		} else {
+			v.SetLen(0)
+		}
+
+		for i := 0; i < n; i++ {
+			if i == v.Cap() {
+				v.Grow(1)
+			}
+			v.SetLen(i + 1)
+			elem := v.Index(i)
+			elem.SetZero()
+			if err := elemDec(d, elem); err != nil {
+				return d.indexError(err, i)
+			}
+		}
+		return nil
+	}
+}
+
+func newArrayDecoder(typ reflect.Type) decoderFunc {
+	elemDec := getDecoder(typ.Elem())
+	return func(d *Decoder, v reflect.Value) error {
+		n, err := d.DecodeArrayLen()
+		if err != nil {
+			return err
+		}
+		if n == -1 && d.strict() {
+			return d.codeError(msgpcode.Nil, "array")
+		}
+		if err := d.enter(); err != nil {
+			return err
+		}
+		defer d.leave()
+
+		for i := 0; i < n; i++ {
+			if i >= v.Len() {
+				if err := d.Skip(); err != nil {
+					return d.indexError(err, i)
+				}
+				continue
+			}
+			if err := elemDec(d, v.Index(i)); err != nil {
+				return d.indexError(err, i)
+			}
+		}
+		for i := max(n, 0); i < v.Len(); i++ {
+			v.Index(i).SetZero()
+		}
+		return nil
+	}
+}
+
+func newMapDecoder(typ reflect.Type) decoderFunc {
+	keyDec := getDecoder(typ.Key())
+	valueDec := getDecoder(typ.Elem())
+	return func(d *Decoder, v reflect.Value) error {
+		n, err := d.DecodeMapLen()
+		if err != nil {
+			return err
+		}
+		if n == -1 {
+			v.SetZero()
+			return nil
+		}
+		if err := d.alloc(n * int(typ.Key().Size()+typ.Elem().Size())); err != nil {
+			return err
+		}
+		if err := d.enter(); err != nil {
+			return err
+		}
+		defer d.leave()
+
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(typ, min(n, maxMapSize)))
+		}
+
+		key := reflect.New(typ.Key()).Elem()
+		value := reflect.New(typ.Elem()).Elem()
+		for i := 0; i < n; i++ {
+			key.SetZero()
+			if err := keyDec(d, key); err != nil {
+				return d.wrapError(err)
+			}
+			value.SetZero()
+			if err := valueDec(d, value); err != nil {
+				return d.keyError(err, fmt.Sprint(key.Interface()))
+			}
+			v.SetMapIndex(key, value)
+		}
+		return nil
+	}
+}
+
+type fieldDecoder struct {
+	field   *field
+	decoder decoderFunc
+}
+
+func newStructDecoder(typ reflect.Type) decoderFunc {
+	fs := getFields(typ)
+	table := make(map[string]fieldDecoder, len(fs.List))
+	for _, f := range fs.List {
+		table[f.name] = fieldDecoder{
+			field:   f,
+			decoder: getDecoder(typ.FieldByIndex(f.index).Type),
+		}
+	}
+
+	return func(d *Decoder, v reflect.Value) error {
+		n, err := d.DecodeMapLen()
+		if err != nil {
+			return err
+		}
+		if n == -1 {
+			if d.strict() {
+				return d.codeError(msgpcode.Nil, "map")
+			}
+			v.SetZero()
+			return nil
+		}
+		if err := d.enter(); err != nil {
+			return err
+		}
+		defer d.leave()
+
+		for i := 0; i < n; i++ {
+			key, err := d.DecodeStringBytes()
+			if err != nil {
+				return d.wrapError(err)
+			}
+
+			fd, ok := table[string(key)]
+			if !ok {
+				if err := d.Skip(); err != nil {
+					return d.wrapError(err)
+				}
+				continue
+			}
+			if err := fd.decoder(d, fd.field.settable(v)); err != nil {
+				return d.keyError(err, fd.field.name)
+			}
+		}
+		return nil
+	}
+}
+
+func newPtrDecoder(typ reflect.Type) decoderFunc {
+	elemDec := getDecoder(typ.Elem())
+	return func(d *Decoder, v reflect.Value) error {
+		c, err := d.PeekCode()
+		if err != nil {
+			return err
+		}
+		if c == msgpcode.Nil {
+			v.SetZero()
+			return d.skipN(1)
+		}
+
+		if v.IsNil() {
+			if err := d.alloc(int(typ.Elem().Size())); err != nil {
+				return err
+			}
+			v.Set(reflect.New(typ.Elem()))
+		}
+		return elemDec(d, v.Elem())
+	}
+}
+
+func newUnsupportedTypeDecoder(typ reflect.Type) decoderFunc {
+	return func(d *Decoder, v reflect.Value) error {
+		return fmt.Errorf("msgpack: Decode(unsupported %s)", typ)
+	}
+}
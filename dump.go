@@ -0,0 +1,392 @@
+package msgpack
+
+import (
+	"io"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nurlybekovnt/msgpack/msgpcode"
+)
+
+const (
+	dumpHeaderWidth = 26 // width of the hex column, enough for an int64
+	dumpRowLen      = 8  // payload bytes per row
+	dumpPreviewLen  = 32 // string bytes shown in the description
+)
+
+// Dump writes an annotated hex dump of the msgpack values in b to w, one line
+// per value, like:
+//
+//	00000000  81                          fixmap len=1
+//	00000001  a2                            fixstr len=2 "id"
+//	00000002  69 64                           |id|
+//	00000004  cd 01 c8                      uint16 456
+//
+// Each line starts with the offset and the header bytes of the value, followed
+// by the code family, the length and the value itself, indented by nesting
+// level. String, bin and ext payloads are printed in rows below their header.
+//
+// Dump is meant for debugging malformed input: a byte that isn't a valid code
+// is reported and skipped, and truncated values, including unfinished
+// containers at the end of b, are reported with the bytes that are present.
+// Dump returns the first decoding error, after dumping all of b, or the first
+// error writing to w.
+func Dump(w io.Writer, b []byte) error {
+	dw := dumper{w: w, d: Decoder{data: b}}
+	dw.dump()
+	if dw.werr != nil {
+		return dw.werr
+	}
+	return dw.err
+}
+
+type dumper struct {
+	w     io.Writer
+	d     Decoder
+	stack []dumpFrame
+	line  []byte
+	err   error // first decoding error
+	werr  error
+}
+
+type dumpFrame struct {
+	off   int // offset of the header
+	n     int // values left, keys and values counted separately for maps
+	isMap bool
+}
+
+func (dw *dumper) dump() {
+	d := &dw.d
+	for dw.werr == nil {
+		// Containers ending here have been printed completely.
+		for len(dw.stack) > 0 && dw.stack[len(dw.stack)-1].n == 0 {
+			dw.stack = dw.stack[:len(dw.stack)-1]
+		}
+		if d.i >= len(d.data) {
+			break
+		}
+		if top := len(dw.stack) - 1; top >= 0 {
+			dw.stack[top].n--
+		}
+		if !dw.value() {
+			// The truncated value is still missing from its container.
+			if top := len(dw.stack) - 1; top >= 0 {
+				dw.stack[top].n++
+			}
+			break
+		}
+	}
+
+	// Report the containers the input ended in, innermost first.
+	for i := len(dw.stack) - 1; i >= 0 && dw.werr == nil; i-- {
+		f := dw.stack[i]
+		d.i = len(d.data)
+		err := d.wrapError(io.ErrUnexpectedEOF)
+		kind := "array"
+		if f.isMap {
+			kind = "map"
+		}
+		note := kind + " at " + strconv.FormatInt(int64(f.off), 16) + " with " + strconv.Itoa(f.n) + " values missing"
+		dw.errorLine(len(d.data), nil, err, note)
+	}
+}
+
+// value dumps the next value. It returns false if the rest of the input can't
+// be dumped.
+func (dw *dumper) value() bool {
+	d := &dw.d
+	start := d.i
+	c, _ := d.readCode()
+	name := codeName(c)
+	depth := len(dw.stack)
+	desc := dw.line[:0]
+
+	var err error
+	switch CodeKind(c) {
+	case NilKind:
+		desc = append(desc, name...)
+	case BoolKind:
+		desc = append(desc, name...)
+	case UintKind:
+		var n uint64
+		n, err = d.uint(c)
+		desc = append(desc, name...)
+		desc = append(desc, ' ')
+		desc = strconv.AppendUint(desc, n, 10)
+	case IntKind:
+		var n int64
+		n, err = d.int(c)
+		desc = append(desc, name...)
+		desc = append(desc, ' ')
+		desc = strconv.AppendInt(desc, n, 10)
+	case FloatKind:
+		var f float64
+		f, err = d.float64(c)
+		bits := 64
+		if c == msgpcode.Float {
+			bits = 32
+		}
+		desc = append(desc, name...)
+		desc = append(desc, ' ')
+		desc = strconv.AppendFloat(desc, f, 'g', -1, bits)
+	case StrKind, BinKind:
+		var n int
+		n, err = d.bytesLen(c)
+		if err != nil {
+			break
+		}
+		desc = append(desc, name...)
+		desc = append(desc, " len="...)
+		desc = strconv.AppendInt(desc, int64(n), 10)
+		if c != msgpcode.Bin8 && c != msgpcode.Bin16 && c != msgpcode.Bin32 {
+			desc = append(desc, ' ')
+			desc = appendPreview(desc, dw.available(n))
+		}
+		dw.line = desc
+		dw.writeLine(start, d.data[start:d.i], depth, desc)
+		return dw.payload(n, depth)
+	case ExtKind:
+		var (
+			extID  int8
+			extLen int
+		)
+		extID, extLen, err = d.extHeader(c)
+		if err != nil {
+			break
+		}
+		desc = append(desc, name...)
+		desc = append(desc, " type="...)
+		desc = strconv.AppendInt(desc, int64(extID), 10)
+		desc = append(desc, " len="...)
+		desc = strconv.AppendInt(desc, int64(extLen), 10)
+		if extID == timeExtID && d.i+extLen <= len(d.data) {
+			td := Decoder{data: d.data[d.i : d.i+extLen]}
+			if tm, err := td.decodeTimeExt(extLen); err == nil {
+				desc = append(desc, ' ')
+				desc = tm.UTC().AppendFormat(desc, time.RFC3339Nano)
+			}
+		}
+		dw.line = desc
+		dw.writeLine(start, d.data[start:d.i], depth, desc)
+		return dw.payload(extLen, depth)
+	case ArrayKind, MapKind:
+		var n int
+		isMap := CodeKind(c) == MapKind
+		if isMap {
+			n, err = d.mapLen(c)
+		} else {
+			n, err = d.arrayLen(c)
+		}
+		if err != nil {
+			break
+		}
+		desc = append(desc, name...)
+		desc = append(desc, " len="...)
+		desc = strconv.AppendInt(desc, int64(n), 10)
+		if isMap {
+			n *= 2
+		}
+		if n > 0 {
+			dw.stack = append(dw.stack, dumpFrame{off: start, n: n, isMap: isMap})
+		}
+	default:
+		// Skip the byte and carry on with the next one.
+		dw.line = desc
+		dw.errorLine(start, d.data[start:d.i], d.codeError(c, ""), "")
+		return true
+	}
+
+	dw.line = desc
+	if err != nil {
+		// The value is truncated.
+		dw.errorLine(start, d.data[start:], err, name)
+		return false
+	}
+	dw.writeLine(start, d.data[start:d.i], depth, desc)
+	return true
+}
+
+// available returns up to n bytes of the payload following the header.
+func (dw *dumper) available(n int) []byte {
+	d := &dw.d
+	return d.data[d.i:min(d.i+n, len(d.data))]
+}
+
+// payload dumps the n bytes of payload following the header. It returns false
+// if the payload is truncated.
+func (dw *dumper) payload(n, depth int) bool {
+	d := &dw.d
+	if n < 0 {
+		n = 0
+	}
+	b := dw.available(n)
+	for i := 0; i < len(b) && dw.werr == nil; i += dumpRowLen {
+		row := b[i:min(i+dumpRowLen, len(b))]
+		desc := append(dw.line[:0], '|')
+		for _, c := range row {
+			if c < 0x20 || c > 0x7e {
+				c = '.'
+			}
+			desc = append(desc, c)
+		}
+		desc = append(desc, '|')
+		dw.line = desc
+		dw.writeLine(d.i+i, row, depth+1, desc)
+	}
+	if len(b) < n {
+		d.i = len(d.data)
+		err := d.wrapError(io.ErrUnexpectedEOF)
+		dw.errorLine(d.i, nil, err, strconv.Itoa(n-len(b))+" payload bytes missing")
+		return false
+	}
+	d.i += n
+	return true
+}
+
+// errorLine writes a line reporting err for the bytes hdr at off. The first
+// error is kept to be returned by Dump.
+func (dw *dumper) errorLine(off int, hdr []byte, err error, note string) {
+	if dw.err == nil {
+		dw.err = err
+	}
+	desc := append(dw.line[:0], "!! "...)
+	desc = append(desc, err.Error()...)
+	if note != "" {
+		desc = append(desc, " ("...)
+		desc = append(desc, note...)
+		desc = append(desc, ')')
+	}
+	dw.line = desc
+	// Long truncated values are cut to the hex column.
+	if limit := dumpHeaderWidth / 3; len(hdr) > limit {
+		hdr = hdr[:limit]
+	}
+	dw.writeLine(off, hdr, len(dw.stack), desc)
+}
+
+// writeLine writes a line with the offset, the hex bytes hdr and desc indented
+// by depth. desc may alias dw.line.
+func (dw *dumper) writeLine(off int, hdr []byte, depth int, desc []byte) {
+	if dw.werr != nil {
+		return
+	}
+	var buf [128]byte
+	line := buf[:0]
+	for i := 7; i >= 0; i-- {
+		line = append(line, hexDigits[off>>(4*i)&0xf])
+	}
+	line = append(line, ' ', ' ')
+	n := len(line)
+	for i, c := range hdr {
+		if i > 0 {
+			line = append(line, ' ')
+		}
+		line = append(line, hexDigits[c>>4], hexDigits[c&0xf])
+	}
+	for len(line) < n+dumpHeaderWidth+2 {
+		line = append(line, ' ')
+	}
+	for i := 0; i < depth; i++ {
+		line = append(line, ' ', ' ')
+	}
+	line = append(line, desc...)
+	line = append(line, '\n')
+	_, dw.werr = dw.w.Write(line)
+}
+
+// appendPreview appends the start of the string s quoted.
+func appendPreview(dst, s []byte) []byte {
+	if len(s) <= dumpPreviewLen {
+		return strconv.AppendQuote(dst, string(s))
+	}
+	n := dumpPreviewLen
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	dst = strconv.AppendQuote(dst, string(s[:n]))
+	return append(dst, "..."...)
+}
+
+// codeName returns the name of the code family of c as used by the msgpack
+// specification.
+func codeName(c byte) string {
+	switch {
+	case c <= msgpcode.PosFixedNumHigh:
+		return "positive fixint"
+	case c >= msgpcode.NegFixedNumLow:
+		return "negative fixint"
+	case msgpcode.IsFixedMap(c):
+		return "fixmap"
+	case msgpcode.IsFixedArray(c):
+		return "fixarray"
+	case msgpcode.IsFixedString(c):
+		return "fixstr"
+	}
+
+	switch c {
+	case msgpcode.Nil:
+		return "nil"
+	case msgpcode.False:
+		return "false"
+	case msgpcode.True:
+		return "true"
+	case msgpcode.Bin8:
+		return "bin8"
+	case msgpcode.Bin16:
+		return "bin16"
+	case msgpcode.Bin32:
+		return "bin32"
+	case msgpcode.Ext8:
+		return "ext8"
+	case msgpcode.Ext16:
+		return "ext16"
+	case msgpcode.Ext32:
+		return "ext32"
+	case msgpcode.Float:
+		return "float32"
+	case msgpcode.Double:
+		return "float64"
+	case msgpcode.Uint8:
+		return "uint8"
+	case msgpcode.Uint16:
+		return "uint16"
+	case msgpcode.Uint32:
+		return "uint32"
+	case msgpcode.Uint64:
+		return "uint64"
+	case msgpcode.Int8:
+		return "int8"
+	case msgpcode.Int16:
+		return "int16"
+	case msgpcode.Int32:
+		return "int32"
+	case msgpcode.Int64:
+		return "int64"
+	case msgpcode.FixExt1:
+		return "fixext1"
+	case msgpcode.FixExt2:
+		return "fixext2"
+	case msgpcode.FixExt4:
+		return "fixext4"
+	case msgpcode.FixExt8:
+		return "fixext8"
+	case msgpcode.FixExt16:
+		return "fixext16"
+	case msgpcode.Str8:
+		return "str8"
+	case msgpcode.Str16:
+		return "str16"
+	case msgpcode.Str32:
+		return "str32"
+	case msgpcode.Array16:
+		return "array16"
+	case msgpcode.Array32:
+		return "array32"
+	case msgpcode.Map16:
+		return "map16"
+	case msgpcode.Map32:
+		return "map32"
+	}
+	return "never used"
+}
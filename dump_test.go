@@ -0,0 +1,83 @@
+package msgpack_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nurlybekovnt/msgpack"
+)
+
+func TestDump(t *testing.T) {
+	v := msgpack.AppendMapLen(nil, 2)
+	v = msgpack.AppendString(v, "id")
+	v = msgpack.AppendUint16(v, 456)
+	v = msgpack.AppendString(v, "data")
+	v = msgpack.AppendBytes(v, []byte("0123456789"))
+
+	const head = `
+00000000  82                          fixmap len=2
+00000001  a2                            fixstr len=2 "id"
+00000002  69 64                           |id|
+00000004  cd 01 c8                      uint16 456
+00000007  a4                            fixstr len=4 "data"
+00000008  64 61 74 61                     |data|
+`
+	tests := []struct {
+		name string
+		data []byte
+		want string
+		err  string
+	}{
+		{
+			"value",
+			v,
+			head + `
+0000000c  c4 0a                         bin8 len=10
+0000000e  30 31 32 33 34 35 36 37         |01234567|
+00000016  38 39                           |89|
+`,
+			"",
+		},
+		{
+			"truncated payload",
+			v[:len(v)-4],
+			head + `
+0000000c  c4 0a                         bin8 len=10
+0000000e  30 31 32 33 34 35               |012345|
+00000014                                !! msgpack: unexpected EOF at offset 20 (4 payload bytes missing)
+00000014                                !! msgpack: unexpected EOF at offset 20 (map at 0 with 1 values missing)
+`,
+			"msgpack: unexpected EOF at offset 20",
+		},
+		{
+			"truncated header",
+			v[:len(v)-11],
+			head + `
+0000000c  c4                            !! msgpack: unexpected EOF at offset 13 (bin8)
+0000000d                                !! msgpack: unexpected EOF at offset 13 (map at 0 with 1 values missing)
+`,
+			"msgpack: unexpected EOF at offset 13",
+		},
+		{
+			"invalid code",
+			[]byte{0xc1, 0x01},
+			`
+00000000  c1                          !! msgpack: unexpected code=c1 (invalid) at offset 1
+00000001  01                          positive fixint 1
+`,
+			"msgpack: unexpected code=c1 (invalid) at offset 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			err := msgpack.Dump(&b, tt.data)
+			if got, want := b.String(), strings.ReplaceAll(tt.want[1:], "\n\n", "\n"); got != want {
+				t.Errorf("Dump() output:\n%s\nwant:\n%s", got, want)
+			}
+			if (err == nil && tt.err != "") || (err != nil && err.Error() != tt.err) {
+				t.Errorf("Dump() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
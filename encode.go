@@ -1,7 +1,7 @@
 package msgpack
 
 import (
-	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/nurlybekovnt/msgpack/msgpcode"
@@ -24,6 +24,7 @@ type Encoder struct {
 // Supported map types are:
 //   - map[string]string
 //   - map[string]interface{}
+//   - any other map with string keys encoded using reflection
 func (e *Encoder) SetSortMapKeys(on bool) {
 	if on {
 		e.flags |= sortMapKeysFlag
@@ -52,6 +53,21 @@ func (e *Encoder) UseCompactFloats(on bool) {
 	}
 }
 
+// Append appends the msgpack encoding of v to dst. Values implementing
+// Marshaler encode themselves. Strings, byte slices, booleans, integers,
+// floats, time.Time, time.Duration, []string, map[string]string and
+// map[string]interface{} are encoded directly; structs, pointers, slices,
+// arrays and maps of any supported type are encoded using reflection. Structs
+// are encoded as maps keyed by field name; the name can be overridden with a
+// `msgpack:"name"` struct tag and a field is skipped with `msgpack:"-"`.
+// Unexported fields are ignored. Fields of embedded structs and of exported
+// embedded struct pointers are inlined; a nil embedded pointer contributes no
+// fields. Conflicting names are resolved like in encoding/json: the least
+// nested field wins, then the only tagged one, and otherwise all fields of
+// that name are dropped.
+//
+// Append panics with an *UnsupportedTypeError if v contains a value of an
+// unsupported type; use TryAppend to get an error instead.
 func (e Encoder) Append(dst []byte, v interface{}) []byte {
 	switch v := v.(type) {
 	case nil:
@@ -104,8 +120,13 @@ func (e Encoder) Append(dst []byte, v interface{}) []byte {
 		} else {
 			return e.AppendMap(dst, v)
 		}
+	case Marshaler:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return e.AppendNil(dst)
+		}
+		return v.AppendMsgpack(dst)
 	default:
-		panic(fmt.Errorf("unsupported type: %T", v))
+		return e.appendValue(dst, reflect.ValueOf(v))
 	}
 }
 
@@ -117,11 +138,11 @@ func (e Encoder) AppendMulti(dst []byte, v ...interface{}) []byte {
 	for _, vv := range v {
 		dst = e.Append(dst, vv)
 	}
-	return nil
+	return dst
 }
 
 func AppendMulti(dst []byte, v ...interface{}) []byte {
-	return DefaultEncoder.AppendMulti(dst, v)
+	return DefaultEncoder.AppendMulti(dst, v...)
 }
 
 func (e Encoder) AppendNil(dst []byte) []byte {
@@ -0,0 +1,94 @@
+package msgpack
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/nurlybekovnt/msgpack/msgpcode"
+)
+
+// finishCompactLimit is the maximum number of bytes Finish shifts to replace a
+// reserved header with a shorter one.
+const finishCompactLimit = 1024
+
+// ContainerHandle refers to an array or map header reserved with
+// AppendArrayStart or AppendMapStart.
+type ContainerHandle struct {
+	off   int // index of the header in dst
+	isMap bool
+}
+
+// AppendArrayStart reserves an Array32 header for an array whose length isn't
+// known yet. The elements are appended next and Finish fills in the length:
+//
+//	dst, h := e.AppendArrayStart(dst)
+//	n := 0
+//	for rows.Next() {
+//		dst = e.Append(dst, row)
+//		n++
+//	}
+//	dst = e.Finish(dst, h, n)
+func (e Encoder) AppendArrayStart(dst []byte) ([]byte, ContainerHandle) {
+	h := ContainerHandle{off: len(dst)}
+	return e.append4(dst, msgpcode.Array32, 0), h
+}
+
+func AppendArrayStart(dst []byte) ([]byte, ContainerHandle) {
+	return DefaultEncoder.AppendArrayStart(dst)
+}
+
+// AppendMapStart is like AppendArrayStart for maps. The length passed to
+// Finish is the number of key-value pairs.
+func (e Encoder) AppendMapStart(dst []byte) ([]byte, ContainerHandle) {
+	h := ContainerHandle{off: len(dst), isMap: true}
+	return e.append4(dst, msgpcode.Map32, 0), h
+}
+
+func AppendMapStart(dst []byte) ([]byte, ContainerHandle) {
+	return DefaultEncoder.AppendMapStart(dst)
+}
+
+// Finish sets the length of the container reserved at h to n. If the contents
+// of the container are short, the header is replaced with the most compact
+// one and the contents are shifted, so the result may be shorter than dst;
+// otherwise the 32-bit header is kept, which is valid but not canonical.
+// Nested containers must be finished before the enclosing ones because
+// shifting invalidates the handles of the containers inside.
+func (e Encoder) Finish(dst []byte, h ContainerHandle, n int) []byte {
+	return e.finish(dst, h, n, finishCompactLimit)
+}
+
+// finish is like Finish but compacts the header if at most compactLimit bytes
+// need to be shifted.
+func (e Encoder) finish(dst []byte, h ContainerHandle, n, compactLimit int) []byte {
+	if n < 0 || uint64(n) > math.MaxUint32 {
+		panic(fmt.Sprintf("msgpack: invalid container length %d", n))
+	}
+
+	var (
+		hdr [5]byte
+		b   []byte
+	)
+	if h.isMap {
+		b = e.AppendMapLen(hdr[:0], n)
+	} else {
+		b = e.AppendArrayLen(hdr[:0], n)
+	}
+
+	if len(b) < 5 && len(dst)-h.off-5 <= compactLimit {
+		copy(dst[h.off:], b)
+		n := copy(dst[h.off+len(b):], dst[h.off+5:])
+		return dst[:h.off+len(b)+n]
+	}
+
+	code := byte(msgpcode.Array32)
+	if h.isMap {
+		code = msgpcode.Map32
+	}
+	e.append4(dst[h.off:h.off], code, uint32(n))
+	return dst
+}
+
+func Finish(dst []byte, h ContainerHandle, n int) []byte {
+	return DefaultEncoder.Finish(dst, h, n)
+}
@@ -0,0 +1,106 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nurlybekovnt/msgpack"
+)
+
+func TestFinish(t *testing.T) {
+	long := strings.Repeat("x", 2000)
+	tests := []struct {
+		name  string
+		isMap bool
+		elems []string // keys and values alternating for maps
+		want  []byte   // header, nil for the 32-bit header
+	}{
+		{"empty array", false, nil, msgpack.AppendArrayLen(nil, 0)},
+		{"array", false, []string{"a", "b"}, msgpack.AppendArrayLen(nil, 2)},
+		{"array16", false, strings.Split(strings.Repeat("a", 20), ""), msgpack.AppendArrayLen(nil, 20)},
+		{"long array", false, []string{long}, nil},
+		{"map", true, []string{"k", "v"}, msgpack.AppendMapLen(nil, 1)},
+		{"long map", true, []string{"k", long}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix := msgpack.AppendString(nil, "prefix")
+			var body []byte
+			for _, s := range tt.elems {
+				body = msgpack.AppendString(body, s)
+			}
+			n := len(tt.elems)
+
+			dst := bytes.Clone(prefix)
+			var h msgpack.ContainerHandle
+			if tt.isMap {
+				dst, h = msgpack.AppendMapStart(dst)
+				n /= 2
+			} else {
+				dst, h = msgpack.AppendArrayStart(dst)
+			}
+			dst = append(dst, body...)
+			dst = msgpack.Finish(dst, h, n)
+
+			hdr := tt.want
+			if hdr == nil {
+				hdr = []byte{0xdd, 0, 0, 0, 0}
+				if tt.isMap {
+					hdr[0] = 0xdf
+				}
+				hdr[3], hdr[4] = byte(n>>8), byte(n)
+			}
+			want := append(append(bytes.Clone(prefix), hdr...), body...)
+			if !bytes.Equal(dst, want) {
+				t.Fatalf("Finish() = %x, want %x", dst, want)
+			}
+			if err := msgpack.ValidSequence(dst); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestFinishNested(t *testing.T) {
+	dst, outer := msgpack.AppendArrayStart(nil)
+	for i := 0; i < 3; i++ {
+		var inner msgpack.ContainerHandle
+		dst, inner = msgpack.AppendMapStart(dst)
+		dst = msgpack.AppendString(dst, "i")
+		dst = msgpack.AppendInt(dst, int64(i))
+		dst = msgpack.Finish(dst, inner, 1)
+	}
+	dst = msgpack.Finish(dst, outer, 3)
+
+	want := msgpack.AppendArrayLen(nil, 3)
+	for i := 0; i < 3; i++ {
+		want = msgpack.AppendMapLen(want, 1)
+		want = msgpack.AppendString(want, "i")
+		want = msgpack.AppendInt(want, int64(i))
+	}
+	if !bytes.Equal(dst, want) {
+		t.Fatalf("Finish() = %x, want %x", dst, want)
+	}
+}
+
+func TestFinishInvalidLength(t *testing.T) {
+	lengths := []int{-1}
+	if strconv.IntSize == 64 {
+		tooLong := uint64(math.MaxUint32 + 1)
+		lengths = append(lengths, int(tooLong))
+	}
+	for _, n := range lengths {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatalf("Finish(%d) didn't panic", n)
+				}
+			}()
+			dst, h := msgpack.AppendArrayStart(nil)
+			msgpack.Finish(dst, h, n)
+		})
+	}
+}
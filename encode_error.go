@@ -0,0 +1,136 @@
+package msgpack
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// UnsupportedTypeError is returned by TryAppend when a value can't be encoded.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+	// Path locates the value in nested containers, e.g. items[3].price. It is
+	// empty for top-level values.
+	Path string
+}
+
+func (err *UnsupportedTypeError) Error() string {
+	if err.Path == "" {
+		return fmt.Sprintf("msgpack: unsupported type: %s", err.Type)
+	}
+	return fmt.Sprintf("msgpack: unsupported type: %s in %s", err.Type, err.Path)
+}
+
+// TryAppend is like Append but returns an error instead of panicking if v
+// contains a value of an unsupported type. On error dst is returned as is,
+// without the partially encoded value.
+func (e Encoder) TryAppend(dst []byte, v interface{}) ([]byte, error) {
+	return tryAppend(dst, v, func(dst []byte) []byte {
+		return e.Append(dst, v)
+	})
+}
+
+// tryAppend returns fn(dst), recovering from an unsupported type in v.
+func tryAppend(dst []byte, v interface{}, fn func([]byte) []byte) (b []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			ute, ok := r.(*UnsupportedTypeError)
+			if !ok {
+				panic(r)
+			}
+			b, err = dst, unsupportedValue(reflect.ValueOf(v), ute)
+		}
+	}()
+	return fn(dst), nil
+}
+
+func TryAppend(dst []byte, v interface{}) ([]byte, error) {
+	return DefaultEncoder.TryAppend(dst, v)
+}
+
+// TryAppendMulti is like AppendMulti but returns an error instead of panicking.
+// On error dst is returned as is, without any of the values.
+func (e Encoder) TryAppendMulti(dst []byte, v ...interface{}) ([]byte, error) {
+	b := dst
+	for i, vv := range v {
+		var err error
+		b, err = e.TryAppend(b, vv)
+		if err != nil {
+			ute := err.(*UnsupportedTypeError)
+			ute.Path = joinPath("["+strconv.Itoa(i)+"]", ute.Path)
+			return dst, ute
+		}
+	}
+	return b, nil
+}
+
+func TryAppendMulti(dst []byte, v ...interface{}) ([]byte, error) {
+	return DefaultEncoder.TryAppendMulti(dst, v...)
+}
+
+// unsupportedValue locates the value of an unsupported type in v. The search
+// only runs after encoding failed, so the encoders don't need to track paths.
+// It falls back to ute if the value is hidden, e.g. inside a Marshaler.
+func unsupportedValue(v reflect.Value, ute *UnsupportedTypeError) *UnsupportedTypeError {
+	if path, typ, ok := findUnsupported(v); ok {
+		return &UnsupportedTypeError{Type: typ, Path: path}
+	}
+	return ute
+}
+
+func findUnsupported(v reflect.Value) (string, reflect.Type, bool) {
+	if !v.IsValid() {
+		return "", nil, false
+	}
+	typ := v.Type()
+	if typ == timeType || getExtByType(typ) != nil || typ.Implements(marshalerType) {
+		return "", nil, false
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "", nil, false
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return "", nil, false
+		}
+		return findUnsupported(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return "", nil, false
+		}
+		for i := 0; i < v.Len(); i++ {
+			if path, typ, ok := findUnsupported(v.Index(i)); ok {
+				return joinPath("["+strconv.Itoa(i)+"]", path), typ, true
+			}
+		}
+		return "", nil, false
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			if path, typ, ok := findUnsupported(iter.Key()); ok {
+				return joinPath(key, path), typ, true
+			}
+			if path, typ, ok := findUnsupported(iter.Value()); ok {
+				return joinPath(key, path), typ, true
+			}
+		}
+		return "", nil, false
+	case reflect.Struct:
+		for _, f := range getFields(typ).List {
+			fv, ok := f.lookup(v)
+			if !ok {
+				continue
+			}
+			if path, typ, ok := findUnsupported(fv); ok {
+				return joinPath(f.name, path), typ, true
+			}
+		}
+		return "", nil, false
+	}
+	return "", typ, true
+}
@@ -0,0 +1,317 @@
+package msgpack
+
+import (
+	"io"
+	"time"
+)
+
+const defaultStreamBufSize = 4096
+
+// StreamEncoder encodes values to an io.Writer. Encoded data is collected in a
+// buffer that is flushed to the writer whenever it fills up, so arbitrary long
+// streams of values can be written with bounded memory. Long strings and byte
+// slices are written to the writer directly instead of being buffered.
+//
+// Errors are sticky: after a write fails every method returns the same error.
+// Call Flush after the last value to write out the buffered data.
+type StreamEncoder struct {
+	enc  Encoder
+	w    io.Writer
+	buf  []byte
+	size int
+	err  error
+}
+
+// NewStreamEncoder returns a new StreamEncoder writing to w with a buffer of
+// bufSize bytes. A default size is used if bufSize is not positive.
+func NewStreamEncoder(w io.Writer, bufSize int) *StreamEncoder {
+	if bufSize <= 0 {
+		bufSize = defaultStreamBufSize
+	}
+	return &StreamEncoder{
+		w:    w,
+		buf:  make([]byte, 0, bufSize),
+		size: bufSize,
+	}
+}
+
+// Reset discards any unflushed data and the sticky error and resets s to write
+// to w.
+func (s *StreamEncoder) Reset(w io.Writer) {
+	s.w = w
+	s.buf = s.buf[:0]
+	s.err = nil
+}
+
+// SetSortMapKeys is like Encoder.SetSortMapKeys.
+func (s *StreamEncoder) SetSortMapKeys(on bool) {
+	s.enc.SetSortMapKeys(on)
+}
+
+// UseCompactInts is like Encoder.UseCompactInts.
+func (s *StreamEncoder) UseCompactInts(on bool) {
+	s.enc.UseCompactInts(on)
+}
+
+// UseCompactFloats is like Encoder.UseCompactFloats.
+func (s *StreamEncoder) UseCompactFloats(on bool) {
+	s.enc.UseCompactFloats(on)
+}
+
+// Buffered returns the number of bytes that have not been flushed yet.
+func (s *StreamEncoder) Buffered() int {
+	return len(s.buf)
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (s *StreamEncoder) Flush() error {
+	if s.err != nil {
+		return s.err
+	}
+	if len(s.buf) == 0 {
+		return nil
+	}
+	_, s.err = s.w.Write(s.buf)
+	s.buf = s.buf[:0]
+	return s.err
+}
+
+// Write writes p verbatim, e.g. the payload after EncodeBytesLen or
+// EncodeExtHeader. It implements io.Writer.
+func (s *StreamEncoder) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	if len(s.buf)+len(p) <= s.size {
+		s.buf = append(s.buf, p...)
+		return len(p), s.flushFull()
+	}
+	if err := s.Flush(); err != nil {
+		return 0, err
+	}
+	var n int
+	n, s.err = s.w.Write(p)
+	return n, s.err
+}
+
+func (s *StreamEncoder) writeString(v string) error {
+	if s.err != nil {
+		return s.err
+	}
+	if len(s.buf)+len(v) <= s.size {
+		s.buf = append(s.buf, v...)
+		return s.flushFull()
+	}
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	_, s.err = io.WriteString(s.w, v)
+	return s.err
+}
+
+// flushFull flushes the buffer if it is full.
+func (s *StreamEncoder) flushFull() error {
+	if s.err != nil {
+		s.buf = s.buf[:0]
+		return s.err
+	}
+	if len(s.buf) >= s.size {
+		return s.Flush()
+	}
+	return nil
+}
+
+// Encode encodes v like Encoder.Append. If v contains a value of an
+// unsupported type, Encode returns an *UnsupportedTypeError and nothing is
+// written; the error is not sticky.
+func (s *StreamEncoder) Encode(v interface{}) error {
+	buf, err := s.enc.TryAppend(s.buf, v)
+	if err != nil {
+		return err
+	}
+	s.buf = buf
+	return s.flushFull()
+}
+
+// EncodeMulti encodes each value of v like Encoder.Append.
+func (s *StreamEncoder) EncodeMulti(v ...interface{}) error {
+	for _, vv := range v {
+		if err := s.Encode(vv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *StreamEncoder) EncodeNil() error {
+	s.buf = s.enc.AppendNil(s.buf)
+	return s.flushFull()
+}
+
+func (s *StreamEncoder) EncodeBool(value bool) error {
+	s.buf = s.enc.AppendBool(s.buf, value)
+	return s.flushFull()
+}
+
+func (s *StreamEncoder) EncodeInt(n int64) error {
+	s.buf = s.enc.AppendInt(s.buf, n)
+	return s.flushFull()
+}
+
+func (s *StreamEncoder) EncodeInt8(n int8) error {
+	s.buf = s.enc.AppendInt8(s.buf, n)
+	return s.flushFull()
+}
+
+func (s *StreamEncoder) EncodeInt16(n int16) error {
+	s.buf = s.enc.AppendInt16(s.buf, n)
+	return s.flushFull()
+}
+
+func (s *StreamEncoder) EncodeInt32(n int32) error {
+	s.buf = s.enc.AppendInt32(s.buf, n)
+	return s.flushFull()
+}
+
+func (s *StreamEncoder) EncodeInt64(n int64) error {
+	s.buf = s.enc.AppendInt64(s.buf, n)
+	return s.flushFull()
+}
+
+func (s *StreamEncoder) EncodeUint(n uint64) error {
+	s.buf = s.enc.AppendUint(s.buf, n)
+	return s.flushFull()
+}
+
+func (s *StreamEncoder) EncodeUint8(n uint8) error {
+	s.buf = s.enc.AppendUint8(s.buf, n)
+	return s.flushFull()
+}
+
+func (s *StreamEncoder) EncodeUint16(n uint16) error {
+	s.buf = s.enc.AppendUint16(s.buf, n)
+	return s.flushFull()
+}
+
+func (s *StreamEncoder) EncodeUint32(n uint32) error {
+	s.buf = s.enc.AppendUint32(s.buf, n)
+	return s.flushFull()
+}
+
+func (s *StreamEncoder) EncodeUint64(n uint64) error {
+	s.buf = s.enc.AppendUint64(s.buf, n)
+	return s.flushFull()
+}
+
+func (s *StreamEncoder) EncodeFloat32(n float32) error {
+	s.buf = s.enc.AppendFloat32(s.buf, n)
+	return s.flushFull()
+}
+
+func (s *StreamEncoder) EncodeFloat64(n float64) error {
+	s.buf = s.enc.AppendFloat64(s.buf, n)
+	return s.flushFull()
+}
+
+func (s *StreamEncoder) EncodeDuration(d time.Duration) error {
+	s.buf = s.enc.AppendDuration(s.buf, d)
+	return s.flushFull()
+}
+
+func (s *StreamEncoder) EncodeTime(tm time.Time) error {
+	s.buf = s.enc.AppendTime(s.buf, tm)
+	return s.flushFull()
+}
+
+func (s *StreamEncoder) EncodeString(v string) error {
+	s.buf = s.enc.appendStringLen(s.buf, len(v))
+	return s.writeString(v)
+}
+
+func (s *StreamEncoder) EncodeBytes(v []byte) error {
+	if v == nil {
+		return s.EncodeNil()
+	}
+	s.buf = s.enc.AppendBytesLen(s.buf, len(v))
+	_, err := s.Write(v)
+	return err
+}
+
+// EncodeBytesLen encodes the header of a byte slice of length l. The l bytes
+// must be written with Write.
+func (s *StreamEncoder) EncodeBytesLen(l int) error {
+	s.buf = s.enc.AppendBytesLen(s.buf, l)
+	return s.flushFull()
+}
+
+// EncodeExtHeader encodes the header of an ext value. The extLen bytes of
+// payload must be written with Write.
+func (s *StreamEncoder) EncodeExtHeader(extID int8, extLen int) error {
+	s.buf = s.enc.AppendExtHeader(s.buf, extID, extLen)
+	return s.flushFull()
+}
+
+// EncodeArrayLen encodes the header of an array of l elements, which must be
+// encoded next.
+func (s *StreamEncoder) EncodeArrayLen(l int) error {
+	s.buf = s.enc.AppendArrayLen(s.buf, l)
+	return s.flushFull()
+}
+
+// EncodeMapLen encodes the header of a map of l key-value pairs, which must be
+// encoded next.
+func (s *StreamEncoder) EncodeMapLen(l int) error {
+	s.buf = s.enc.AppendMapLen(s.buf, l)
+	return s.flushFull()
+}
+
+func (s *StreamEncoder) EncodeStringSlice(v []string) error {
+	if v == nil {
+		return s.EncodeNil()
+	}
+	if err := s.EncodeArrayLen(len(v)); err != nil {
+		return err
+	}
+	for _, vv := range v {
+		if err := s.EncodeString(vv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodeMap encodes m like Encoder.AppendMap. Unsupported values are reported
+// like by Encode.
+func (s *StreamEncoder) EncodeMap(m map[string]interface{}) error {
+	buf, err := tryAppend(s.buf, m, func(dst []byte) []byte {
+		return s.enc.AppendMap(dst, m)
+	})
+	if err != nil {
+		return err
+	}
+	s.buf = buf
+	return s.flushFull()
+}
+
+// EncodeMapSorted encodes m like Encoder.AppendMapSorted. Unsupported values
+// are reported like by Encode.
+func (s *StreamEncoder) EncodeMapSorted(m map[string]interface{}) error {
+	buf, err := tryAppend(s.buf, m, func(dst []byte) []byte {
+		return s.enc.AppendMapSorted(dst, m)
+	})
+	if err != nil {
+		return err
+	}
+	s.buf = buf
+	return s.flushFull()
+}
+
+// EncodeRaw writes the already encoded value m verbatim, or nil if m is
+// empty.
+func (s *StreamEncoder) EncodeRaw(m RawMessage) error {
+	if len(m) == 0 {
+		return s.EncodeNil()
+	}
+	_, err := s.Write(m)
+	return err
+}
@@ -0,0 +1,47 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/nurlybekovnt/msgpack"
+)
+
+func TestStreamEncoderUnsupported(t *testing.T) {
+	m := map[string]interface{}{"ok": 1, "bad": make(chan int)}
+	tests := []struct {
+		name   string
+		encode func(s *msgpack.StreamEncoder) error
+	}{
+		{"Encode", func(s *msgpack.StreamEncoder) error { return s.Encode(m) }},
+		{"EncodeMap", func(s *msgpack.StreamEncoder) error { return s.EncodeMap(m) }},
+		{"EncodeMapSorted", func(s *msgpack.StreamEncoder) error { return s.EncodeMapSorted(m) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			s := msgpack.NewStreamEncoder(&buf, 0)
+			if err := s.EncodeString("before"); err != nil {
+				t.Fatal(err)
+			}
+
+			var ute *msgpack.UnsupportedTypeError
+			if err := tt.encode(s); !errors.As(err, &ute) || ute.Path != "bad" {
+				t.Fatalf("error = %v, want unsupported type in bad", err)
+			}
+
+			// The error isn't sticky and nothing of the map was written.
+			if err := s.EncodeString("after"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			want := msgpack.AppendString(msgpack.AppendString(nil, "before"), "after")
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Fatalf("written %x, want %x", buf.Bytes(), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package msgpack
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+type encoderFunc func(e Encoder, dst []byte, v reflect.Value) []byte
+
+var encoders sync.Map // map[reflect.Type]encoderFunc
+
+var marshalerType = reflect.TypeOf((*Marshaler)(nil)).Elem()
+
+func (e Encoder) appendValue(dst []byte, v reflect.Value) []byte {
+	if !v.IsValid() {
+		return e.AppendNil(dst)
+	}
+	return getEncoder(v.Type())(e, dst, v)
+}
+
+// getEncoder returns the cached encoder for typ. Recursive types are resolved
+// with an indirect encoder that waits until the real one is built.
+func getEncoder(typ reflect.Type) encoderFunc {
+	if fn, ok := encoders.Load(typ); ok {
+		return fn.(encoderFunc)
+	}
+
+	var (
+		wg sync.WaitGroup
+		fn encoderFunc
+	)
+	wg.Add(1)
+	fi, loaded := encoders.LoadOrStore(typ, encoderFunc(func(e Encoder, dst []byte, v reflect.Value) []byte {
+		wg.Wait()
+		return fn(e, dst, v)
+	}))
+	if loaded {
+		return fi.(encoderFunc)
+	}
+
+	fn = newEncoder(typ)
+	wg.Done()
+	encoders.Store(typ, fn)
+	return fn
+}
+
+func newEncoder(typ reflect.Type) encoderFunc {
+	if info := getExtByType(typ); info != nil {
+		return newExtEncoder(info)
+	}
+	if typ.Implements(marshalerType) {
+		return appendMarshalerValue
+	}
+	if typ.Kind() != reflect.Pointer && reflect.PointerTo(typ).Implements(marshalerType) {
+		return newAddrMarshalerEncoder(newKindEncoder(typ))
+	}
+	return newKindEncoder(typ)
+}
+
+func newKindEncoder(typ reflect.Type) encoderFunc {
+	if typ == timeType {
+		return appendTimeValue
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return appendBoolValue
+	case reflect.Int:
+		return appendIntValue
+	case reflect.Int8:
+		return appendInt8Value
+	case reflect.Int16:
+		return appendInt16Value
+	case reflect.Int32:
+		return appendInt32Value
+	case reflect.Int64:
+		return appendInt64Value
+	case reflect.Uint:
+		return appendUintValue
+	case reflect.Uint8:
+		return appendUint8Value
+	case reflect.Uint16:
+		return appendUint16Value
+	case reflect.Uint32:
+		return appendUint32Value
+	case reflect.Uint64:
+		return appendUint64Value
+	case reflect.Float32:
+		return appendFloat32Value
+	case reflect.Float64:
+		return appendFloat64Value
+	case reflect.String:
+		return appendStringValue
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return appendBytesValue
+		}
+		return newSliceEncoder(typ)
+	case reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return appendByteArrayValue
+		}
+		return newArrayEncoder(typ)
+	case reflect.Map:
+		return newMapEncoder(typ)
+	case reflect.Struct:
+		return newStructEncoder(typ)
+	case reflect.Pointer:
+		return newPtrEncoder(typ)
+	case reflect.Interface:
+		return appendInterfaceValue
+	}
+	return newUnsupportedTypeEncoder(typ)
+}
+
+func appendMarshalerValue(e Encoder, dst []byte, v reflect.Value) []byte {
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return e.AppendNil(dst)
+	}
+	if v.Kind() != reflect.Pointer && v.CanAddr() {
+		// Avoid copying the value into an interface.
+		return v.Addr().Interface().(Marshaler).AppendMsgpack(dst)
+	}
+	return v.Interface().(Marshaler).AppendMsgpack(dst)
+}
+
+// newAddrMarshalerEncoder returns an encoder for types that implement
+// Marshaler with a pointer receiver. Non-addressable values fall back to the
+// regular encoder.
+func newAddrMarshalerEncoder(fallback encoderFunc) encoderFunc {
+	return func(e Encoder, dst []byte, v reflect.Value) []byte {
+		if !v.CanAddr() {
+			return fallback(e, dst, v)
+		}
+		return v.Addr().Interface().(Marshaler).AppendMsgpack(dst)
+	}
+}
+
+func appendTimeValue(e Encoder, dst []byte, v reflect.Value) []byte {
+	return e.AppendTime(dst, v.Interface().(time.Time))
+}
+
+func appendBoolValue(e Encoder, dst []byte, v reflect.Value) []byte {
+	return e.AppendBool(dst, v.Bool())
+}
+
+func appendIntValue(e Encoder, dst []byte, v reflect.Value) []byte {
+	return e.AppendInt(dst, v.Int())
+}
+
+func appendInt8Value(e Encoder, dst []byte, v reflect.Value) []byte {
+	return e.appendInt8Cond(dst, int8(v.Int()))
+}
+
+func appendInt16Value(e Encoder, dst []byte, v reflect.Value) []byte {
+	return e.appendInt16Cond(dst, int16(v.Int()))
+}
+
+func appendInt32Value(e Encoder, dst []byte, v reflect.Value) []byte {
+	return e.appendInt32Cond(dst, int32(v.Int()))
+}
+
+func appendInt64Value(e Encoder, dst []byte, v reflect.Value) []byte {
+	return e.appendInt64Cond(dst, v.Int())
+}
+
+func appendUintValue(e Encoder, dst []byte, v reflect.Value) []byte {
+	return e.AppendUint(dst, v.Uint())
+}
+
+func appendUint8Value(e Encoder, dst []byte, v reflect.Value) []byte {
+	return e.appendUint8Cond(dst, uint8(v.Uint()))
+}
+
+func appendUint16Value(e Encoder, dst []byte, v reflect.Value) []byte {
+	return e.appendUint16Cond(dst, uint16(v.Uint()))
+}
+
+func appendUint32Value(e Encoder, dst []byte, v reflect.Value) []byte {
+	return e.appendUint32Cond(dst, uint32(v.Uint()))
+}
+
+func appendUint64Value(e Encoder, dst []byte, v reflect.Value) []byte {
+	return e.appendUint64Cond(dst, v.Uint())
+}
+
+func appendFloat32Value(e Encoder, dst []byte, v reflect.Value) []byte {
+	return e.AppendFloat32(dst, float32(v.Float()))
+}
+
+func appendFloat64Value(e Encoder, dst []byte, v reflect.Value) []byte {
+	return e.AppendFloat64(dst, v.Float())
+}
+
+func appendStringValue(e Encoder, dst []byte, v reflect.Value) []byte {
+	return e.AppendString(dst, v.String())
+}
+
+func appendBytesValue(e Encoder, dst []byte, v reflect.Value) []byte {
+	return e.AppendBytes(dst, v.Bytes())
+}
+
+func appendByteArrayValue(e Encoder, dst []byte, v reflect.Value) []byte {
+	dst = e.AppendBytesLen(dst, v.Len())
+	if v.CanAddr() {
+		return append(dst, v.Bytes()...)
+	}
+	for i := 0; i < v.Len(); i++ {
+		dst = append(dst, byte(v.Index(i).Uint()))
+	}
+	return dst
+}
+
+func appendInterfaceValue(e Encoder, dst []byte, v reflect.Value) []byte {
+	if v.IsNil() {
+		return e.AppendNil(dst)
+	}
+	return e.Append(dst, v.Interface())
+}
+
+func newSliceEncoder(typ reflect.Type) encoderFunc {
+	elemEnc := getEncoder(typ.Elem())
+	return func(e Encoder, dst []byte, v reflect.Value) []byte {
+		if v.IsNil() {
+			return e.AppendNil(dst)
+		}
+		n := v.Len()
+		dst = e.AppendArrayLen(dst, n)
+		for i := 0; i < n; i++ {
+			dst = elemEnc(e, dst, v.Index(i))
+		}
+		return dst
+	}
+}
+
+func newArrayEncoder(typ reflect.Type) encoderFunc {
+	elemEnc := getEncoder(typ.Elem())
+	return func(e Encoder, dst []byte, v reflect.Value) []byte {
+		n := v.Len()
+		dst = e.AppendArrayLen(dst, n)
+		for i := 0; i < n; i++ {
+			dst = elemEnc(e, dst, v.Index(i))
+		}
+		return dst
+	}
+}
+
+func newMapEncoder(typ reflect.Type) encoderFunc {
+	keyEnc := getEncoder(typ.Key())
+	valueEnc := getEncoder(typ.Elem())
+	stringKeys := typ.Key().Kind() == reflect.String
+
+	return func(e Encoder, dst []byte, v reflect.Value) []byte {
+		if v.IsNil() {
+			return e.AppendNil(dst)
+		}
+		dst = e.AppendMapLen(dst, v.Len())
+
+		if stringKeys && e.flags&sortMapKeysFlag != 0 {
+			keys := v.MapKeys()
+			sort.Slice(keys, func(i, j int) bool {
+				return keys[i].String() < keys[j].String()
+			})
+			for _, k := range keys {
+				dst = keyEnc(e, dst, k)
+				dst = valueEnc(e, dst, v.MapIndex(k))
+			}
+			return dst
+		}
+
+		iter := v.MapRange()
+		for iter.Next() {
+			dst = keyEnc(e, dst, iter.Key())
+			dst = valueEnc(e, dst, iter.Value())
+		}
+		return dst
+	}
+}
+
+func newStructEncoder(typ reflect.Type) encoderFunc {
+	fs := getFields(typ)
+	encs := make([]encoderFunc, len(fs.List))
+	for i, f := range fs.List {
+		encs[i] = getEncoder(typ.FieldByIndex(f.index).Type)
+	}
+
+	if fs.ptr {
+		return func(e Encoder, dst []byte, v reflect.Value) []byte {
+			// Fields of nil embedded struct pointers are omitted.
+			n := 0
+			for _, f := range fs.List {
+				if _, ok := f.lookup(v); ok {
+					n++
+				}
+			}
+			dst = e.AppendMapLen(dst, n)
+			for i, f := range fs.List {
+				if fv, ok := f.lookup(v); ok {
+					dst = e.AppendString(dst, f.name)
+					dst = encs[i](e, dst, fv)
+				}
+			}
+			return dst
+		}
+	}
+
+	return func(e Encoder, dst []byte, v reflect.Value) []byte {
+		dst = e.AppendMapLen(dst, len(fs.List))
+		for i, f := range fs.List {
+			dst = e.AppendString(dst, f.name)
+			dst = encs[i](e, dst, f.value(v))
+		}
+		return dst
+	}
+}
+
+func newPtrEncoder(typ reflect.Type) encoderFunc {
+	elemEnc := getEncoder(typ.Elem())
+	return func(e Encoder, dst []byte, v reflect.Value) []byte {
+		if v.IsNil() {
+			return e.AppendNil(dst)
+		}
+		return elemEnc(e, dst, v.Elem())
+	}
+}
+
+func newUnsupportedTypeEncoder(typ reflect.Type) encoderFunc {
+	return func(e Encoder, dst []byte, v reflect.Value) []byte {
+		panic(&UnsupportedTypeError{Type: typ})
+	}
+}
@@ -3,10 +3,223 @@ package msgpack
 import (
 	"fmt"
 	"math"
+	"reflect"
+	"sync"
 
 	"github.com/nurlybekovnt/msgpack/msgpcode"
 )
 
+type extInfo struct {
+	id     int8
+	typ    reflect.Type
+	encode func(dst []byte, v reflect.Value) []byte
+	decode func(v reflect.Value, b []byte) error
+}
+
+var (
+	extTypes sync.Map // map[int8]*extInfo
+	typeExts sync.Map // map[reflect.Type]*extInfo
+)
+
+// RegisterExt registers type T as the msgpack extension extID. enc appends the
+// ext payload of v, without the ext header, to dst and dec decodes the payload
+// b into v. Append, Decode and DecodeInterface use the registered functions for
+// values of type T, pointers to T and ext values with the given id.
+//
+// The payload b passed to dec is a copy that dec may retain, unless unsafe
+// decoding is enabled, in which case it references the decoder input.
+//
+// Application extensions use ids 0 to 127; the negative ids are reserved by
+// the msgpack specification, -1 for timestamps.
+//
+// RegisterExt must be called before values of type T are encoded or decoded,
+// typically from an init function. It panics if extID is reserved or if extID
+// or T is already registered.
+func RegisterExt[T any](
+	extID int8,
+	enc func(dst []byte, v *T) []byte,
+	dec func(v *T, b []byte) error,
+) {
+	if extID < 0 {
+		panic(fmt.Errorf("msgpack: ext id=%d is reserved", extID))
+	}
+
+	info := &extInfo{
+		id:  extID,
+		typ: reflect.TypeOf((*T)(nil)).Elem(),
+		encode: func(dst []byte, v reflect.Value) []byte {
+			if v.CanAddr() {
+				return enc(dst, v.Addr().Interface().(*T))
+			}
+			vv := v.Interface().(T)
+			return enc(dst, &vv)
+		},
+		decode: func(v reflect.Value, b []byte) error {
+			return dec(v.Addr().Interface().(*T), b)
+		},
+	}
+
+	// Codecs built for T are cached, so registrations can't be replaced.
+	if old, loaded := extTypes.LoadOrStore(extID, info); loaded {
+		panic(fmt.Errorf("msgpack: ext id=%d is already registered for %s", extID, old.(*extInfo).typ))
+	}
+	if old, loaded := typeExts.LoadOrStore(info.typ, info); loaded {
+		extTypes.Delete(extID)
+		panic(fmt.Errorf("msgpack: %s is already registered as ext id=%d", info.typ, old.(*extInfo).id))
+	}
+}
+
+func getExtByType(typ reflect.Type) *extInfo {
+	if v, ok := typeExts.Load(typ); ok {
+		return v.(*extInfo)
+	}
+	return nil
+}
+
+func getExtByID(extID int8) *extInfo {
+	if v, ok := extTypes.Load(extID); ok {
+		return v.(*extInfo)
+	}
+	return nil
+}
+
+func newExtEncoder(info *extInfo) encoderFunc {
+	return func(e Encoder, dst []byte, v reflect.Value) []byte {
+		// The payload is appended first and then shifted to make room for the
+		// header, whose size depends on the payload length.
+		start := len(dst)
+		dst = info.encode(dst, v)
+		n := len(dst) - start
+
+		var buf [6]byte
+		hdr := e.AppendExtHeader(buf[:0], info.id, n)
+		dst = append(dst, hdr...)
+		copy(dst[start+len(hdr):], dst[start:start+n])
+		copy(dst[start:], hdr)
+		return dst
+	}
+}
+
+func newExtDecoder(info *extInfo) decoderFunc {
+	return func(d *Decoder, v reflect.Value) error {
+		c, err := d.readCode()
+		if err != nil {
+			return err
+		}
+		if c == msgpcode.Nil {
+			if d.strict() && !nilable(info.typ) {
+				return d.codeError(c, "ext")
+			}
+			v.SetZero()
+			return nil
+		}
+
+		extID, extLen, err := d.extHeader(c)
+		if err != nil {
+			return err
+		}
+		if extID != info.id {
+			return d.wrapError(fmt.Errorf("msgpack: invalid ext id=%d decoding %s", extID, info.typ))
+		}
+
+		b, err := d.extData(extLen)
+		if err != nil {
+			return err
+		}
+		return info.decode(v, b)
+	}
+}
+
+func (d *Decoder) decodeInterfaceExt(c byte) (interface{}, error) {
+	extID, extLen, err := d.extHeader(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if extID == timeExtID {
+		return d.decodeTimeExt(extLen)
+	}
+
+	info := getExtByID(extID)
+	if info == nil {
+		return d.rawExtData(extID, extLen)
+	}
+
+	b, err := d.extData(extLen)
+	if err != nil {
+		return nil, err
+	}
+	v := reflect.New(info.typ).Elem()
+	if err := info.decode(v, b); err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// RawExt is a msgpack extension value with an ext id that is not registered
+// with RegisterExt. DecodeInterface returns unknown extensions as RawExt and
+// Append encodes RawExt back, so unknown extensions survive a decode/encode
+// round trip. The round trip is byte-for-byte as long as the original ext
+// header uses the most compact encoding for the payload length.
+type RawExt struct {
+	Type int8
+	Data []byte
+}
+
+var _ Marshaler = RawExt{}
+var _ Unmarshaler = (*RawExt)(nil)
+
+// AppendMsgpack implements Marshaler.
+func (ext RawExt) AppendMsgpack(dst []byte) []byte {
+	dst = AppendExtHeader(dst, ext.Type, len(ext.Data))
+	return append(dst, ext.Data...)
+}
+
+// DecodeMsgpack implements Unmarshaler.
+//
+// Enabling the UnsafeDecoding flag causes Data to reference the underlying
+// decoding byte slice.
+func (ext *RawExt) DecodeMsgpack(d *Decoder) error {
+	c, err := d.readCode()
+	if err != nil {
+		return err
+	}
+	*ext, err = d.rawExt(c)
+	return err
+}
+
+func (d *Decoder) rawExt(c byte) (RawExt, error) {
+	extID, extLen, err := d.extHeader(c)
+	if err != nil {
+		return RawExt{}, err
+	}
+	return d.rawExtData(extID, extLen)
+}
+
+func (d *Decoder) rawExtData(extID int8, extLen int) (RawExt, error) {
+	b, err := d.extData(extLen)
+	if err != nil {
+		return RawExt{}, err
+	}
+	return RawExt{Type: extID, Data: b}, nil
+}
+
+// extData reads an ext payload of extLen bytes. The payload is copied unless
+// unsafe decoding is enabled.
+func (d *Decoder) extData(extLen int) ([]byte, error) {
+	b, err := d.readN(extLen)
+	if err != nil {
+		return nil, err
+	}
+	if !d.unsafeDecoding() {
+		if err := d.alloc(extLen); err != nil {
+			return nil, err
+		}
+		b = append([]byte(nil), b...)
+	}
+	return b, nil
+}
+
 func (e Encoder) AppendExtHeader(dst []byte, extID int8, extLen int) []byte {
 	dst = e.appendExtLen(dst, extLen)
 	return append(dst, byte(extID))
@@ -52,7 +265,7 @@ func (d *Decoder) extHeader(c byte) (int8, int, error) {
 		return 0, 0, err
 	}
 
-	extID, err := d.readCode()
+	extID, err := d.uint8()
 	if err != nil {
 		return 0, 0, err
 	}
@@ -61,29 +274,40 @@ func (d *Decoder) extHeader(c byte) (int8, int, error) {
 }
 
 func (d *Decoder) parseExtLen(c byte) (int, error) {
+	var size int
 	switch c {
 	case msgpcode.FixExt1:
-		return 1, nil
+		size = 1
 	case msgpcode.FixExt2:
-		return 2, nil
+		size = 2
 	case msgpcode.FixExt4:
-		return 4, nil
+		size = 4
 	case msgpcode.FixExt8:
-		return 8, nil
+		size = 8
 	case msgpcode.FixExt16:
-		return 16, nil
+		size = 16
 	case msgpcode.Ext8:
 		n, err := d.uint8()
-		return int(n), err
+		if err != nil {
+			return 0, err
+		}
+		size = int(n)
 	case msgpcode.Ext16:
 		n, err := d.uint16()
-		return int(n), err
+		if err != nil {
+			return 0, err
+		}
+		size = int(n)
 	case msgpcode.Ext32:
 		n, err := d.uint32()
-		return int(n), err
+		if err != nil {
+			return 0, err
+		}
+		size = int(n)
 	default:
-		return 0, fmt.Errorf("msgpack: invalid code=%x decoding ext len", c)
+		return 0, d.codeError(c, "ext")
 	}
+	return size, d.checkLimit("MaxExtLen", size, d.limits.MaxExtLen)
 }
 
 func (d *Decoder) skipExt(c byte) error {
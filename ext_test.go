@@ -0,0 +1,139 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nurlybekovnt/msgpack"
+)
+
+const blobExtID = 10
+
+// blob retains the payload passed to its decoder.
+type blob struct {
+	data []byte
+}
+
+func init() {
+	msgpack.RegisterExt(blobExtID,
+		func(dst []byte, v *blob) []byte { return append(dst, v.data...) },
+		func(v *blob, b []byte) error { v.data = b; return nil },
+	)
+}
+
+func TestRegisterExtPanics(t *testing.T) {
+	type other struct{}
+	tests := []struct {
+		name     string
+		register func()
+		panic    string
+	}{
+		{"timestamp", func() { msgpack.RegisterExt[other](-1, nil, nil) }, "ext id=-1 is reserved"},
+		{"reserved", func() { msgpack.RegisterExt[other](-128, nil, nil) }, "ext id=-128 is reserved"},
+		{"id registered", func() { msgpack.RegisterExt[other](blobExtID, nil, nil) }, "ext id=10 is already registered"},
+		{"type registered", func() { msgpack.RegisterExt[blob](blobExtID+1, nil, nil) }, "is already registered as ext id=10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				err, ok := r.(error)
+				if !ok || !strings.Contains(err.Error(), tt.panic) {
+					t.Fatalf("RegisterExt() panic = %v, want %q", r, tt.panic)
+				}
+			}()
+			tt.register()
+		})
+	}
+
+	// The failed registrations didn't replace the existing one.
+	b := msgpack.Append(nil, blob{data: []byte("x")})
+	var v interface{}
+	if err := msgpack.NewDecoder(b).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.(blob); !ok {
+		t.Fatalf("DecodeInterface() = %T, want blob", v)
+	}
+}
+
+func TestExtPayloadCopied(t *testing.T) {
+	payload := []byte("payload")
+	tests := []struct {
+		name   string
+		data   []byte
+		decode func(d *msgpack.Decoder) ([]byte, error)
+	}{
+		{
+			"Decode",
+			msgpack.Append(nil, blob{data: payload}),
+			func(d *msgpack.Decoder) ([]byte, error) {
+				var v blob
+				err := d.Decode(&v)
+				return v.data, err
+			},
+		},
+		{
+			"DecodeInterface",
+			msgpack.Append(nil, blob{data: payload}),
+			func(d *msgpack.Decoder) ([]byte, error) {
+				v, err := d.DecodeInterface()
+				b, _ := v.(blob)
+				return b.data, err
+			},
+		},
+		{
+			"RawExt",
+			msgpack.Append(nil, msgpack.RawExt{Type: 42, Data: payload}),
+			func(d *msgpack.Decoder) ([]byte, error) {
+				v, err := d.DecodeInterface()
+				ext, _ := v.(msgpack.RawExt)
+				return ext.Data, err
+			},
+		},
+	}
+	for _, tt := range tests {
+		for _, unsafe := range []bool{false, true} {
+			name := tt.name
+			if unsafe {
+				name += "/unsafe"
+			}
+			t.Run(name, func(t *testing.T) {
+				data := bytes.Clone(tt.data)
+				d := msgpack.NewDecoder(data)
+				d.UseUnsafeDecoding(unsafe)
+				got, err := tt.decode(d)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, payload) {
+					t.Fatalf("payload = %q, want %q", got, payload)
+				}
+
+				for i := range data {
+					data[i] = 0
+				}
+				if aliased := !bytes.Equal(got, payload); aliased != unsafe {
+					t.Fatalf("payload references the input: %v, want %v", aliased, unsafe)
+				}
+			})
+		}
+	}
+}
+
+func TestExtRoundTrip(t *testing.T) {
+	in := map[string]interface{}{
+		"blob": blob{data: []byte{1, 2, 3}},
+		"raw":  msgpack.RawExt{Type: 42, Data: []byte{4, 5}},
+	}
+	b := msgpack.Append(nil, in)
+	out, err := msgpack.NewDecoder(b).DecodeMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("DecodeMap() = %v, want %v", out, in)
+	}
+}
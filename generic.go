@@ -0,0 +1,223 @@
+package msgpack
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"unsafe"
+)
+
+// Scalar is the set of types with a native msgpack encoding supported by the
+// generic slice and map helpers. Named types are supported as well.
+type Scalar interface {
+	~bool | ~string |
+		~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// AppendSlice appends s as a msgpack array, or nil if s is nil. Elements are
+// encoded like Encoder.Append does, but without boxing them into interfaces.
+// Note that []byte is encoded as an array of integers; use AppendBytes to
+// encode binary data.
+//
+// Go methods can't have type parameters, so the Encoder is passed explicitly;
+// use DefaultEncoder for the default behaviour.
+func AppendSlice[T Scalar](e Encoder, dst []byte, s []T) []byte {
+	if s == nil {
+		return e.AppendNil(dst)
+	}
+
+	kind := scalarKind[T]()
+	dst = e.AppendArrayLen(dst, len(s))
+	for i := range s {
+		dst = appendScalar(e, dst, kind, &s[i])
+	}
+	return dst
+}
+
+// AppendMapOf appends m as a msgpack map, or nil if m is nil. Keys and values
+// are encoded like Encoder.Append does, but without boxing them into
+// interfaces. Keys of string kind are sorted if e sorts map keys.
+func AppendMapOf[K, V Scalar](e Encoder, dst []byte, m map[K]V) []byte {
+	if m == nil {
+		return e.AppendNil(dst)
+	}
+
+	keyKind := scalarKind[K]()
+	valueKind := scalarKind[V]()
+	dst = e.AppendMapLen(dst, len(m))
+
+	if keyKind == reflect.String && e.flags&sortMapKeysFlag != 0 {
+		keys := make([]K, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return *(*string)(unsafe.Pointer(&keys[i])) < *(*string)(unsafe.Pointer(&keys[j]))
+		})
+		for i := range keys {
+			v := m[keys[i]]
+			dst = appendScalar(e, dst, keyKind, &keys[i])
+			dst = appendScalar(e, dst, valueKind, &v)
+		}
+		return dst
+	}
+
+	for k, v := range m {
+		dst = appendScalar(e, dst, keyKind, &k)
+		dst = appendScalar(e, dst, valueKind, &v)
+	}
+	return dst
+}
+
+// DecodeSliceOf decodes a msgpack array into a []T. It returns nil if the
+// array is nil.
+func DecodeSliceOf[T Scalar](d *Decoder) ([]T, error) {
+	n, err := d.DecodeArrayLen()
+	if err != nil {
+		return nil, err
+	}
+	if n == -1 {
+		return nil, nil
+	}
+
+	var zero T
+	if err := d.alloc(n * int(unsafe.Sizeof(zero))); err != nil {
+		return nil, err
+	}
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer d.leave()
+
+	kind := scalarKind[T]()
+	s := make([]T, 0, min(n, sliceAllocLimit))
+	for i := 0; i < n; i++ {
+		var v T
+		if err := decodeScalar(d, kind, &v); err != nil {
+			return nil, d.indexError(err, i)
+		}
+		s = append(s, v)
+	}
+	return s, nil
+}
+
+// DecodeMapOf decodes a msgpack map into a map[K]V. It returns nil if the map
+// is nil.
+func DecodeMapOf[K, V Scalar](d *Decoder) (map[K]V, error) {
+	n, err := d.DecodeMapLen()
+	if err != nil {
+		return nil, err
+	}
+	if n == -1 {
+		return nil, nil
+	}
+
+	var (
+		zeroK K
+		zeroV V
+	)
+	if err := d.alloc(n * int(unsafe.Sizeof(zeroK)+unsafe.Sizeof(zeroV))); err != nil {
+		return nil, err
+	}
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer d.leave()
+
+	keyKind := scalarKind[K]()
+	valueKind := scalarKind[V]()
+	m := make(map[K]V, min(n, maxMapSize))
+	for i := 0; i < n; i++ {
+		var (
+			k K
+			v V
+		)
+		if err := decodeScalar(d, keyKind, &k); err != nil {
+			return nil, d.wrapError(err)
+		}
+		if err := decodeScalar(d, valueKind, &v); err != nil {
+			return nil, d.keyError(err, fmt.Sprint(k))
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+func scalarKind[T Scalar]() reflect.Kind {
+	var zero T
+	return reflect.TypeOf(zero).Kind()
+}
+
+// appendScalar appends *v, whose underlying type is given by kind.
+func appendScalar[T Scalar](e Encoder, dst []byte, kind reflect.Kind, v *T) []byte {
+	p := unsafe.Pointer(v)
+	switch kind {
+	case reflect.Bool:
+		return e.AppendBool(dst, *(*bool)(p))
+	case reflect.String:
+		return e.AppendString(dst, *(*string)(p))
+	case reflect.Int:
+		return e.AppendInt(dst, int64(*(*int)(p)))
+	case reflect.Int8:
+		return e.appendInt8Cond(dst, *(*int8)(p))
+	case reflect.Int16:
+		return e.appendInt16Cond(dst, *(*int16)(p))
+	case reflect.Int32:
+		return e.appendInt32Cond(dst, *(*int32)(p))
+	case reflect.Int64:
+		return e.appendInt64Cond(dst, *(*int64)(p))
+	case reflect.Uint:
+		return e.AppendUint(dst, uint64(*(*uint)(p)))
+	case reflect.Uint8:
+		return e.appendUint8Cond(dst, *(*uint8)(p))
+	case reflect.Uint16:
+		return e.appendUint16Cond(dst, *(*uint16)(p))
+	case reflect.Uint32:
+		return e.appendUint32Cond(dst, *(*uint32)(p))
+	case reflect.Uint64:
+		return e.appendUint64Cond(dst, *(*uint64)(p))
+	case reflect.Float32:
+		return e.AppendFloat32(dst, *(*float32)(p))
+	default:
+		return e.AppendFloat64(dst, *(*float64)(p))
+	}
+}
+
+// decodeScalar decodes into *v, whose underlying type is given by kind.
+func decodeScalar[T Scalar](d *Decoder, kind reflect.Kind, v *T) error {
+	p := unsafe.Pointer(v)
+	var err error
+	switch kind {
+	case reflect.Bool:
+		*(*bool)(p), err = d.DecodeBool()
+	case reflect.String:
+		*(*string)(p), err = d.DecodeString()
+	case reflect.Int:
+		*(*int)(p), err = d.DecodeInt()
+	case reflect.Int8:
+		*(*int8)(p), err = d.DecodeInt8()
+	case reflect.Int16:
+		*(*int16)(p), err = d.DecodeInt16()
+	case reflect.Int32:
+		*(*int32)(p), err = d.DecodeInt32()
+	case reflect.Int64:
+		*(*int64)(p), err = d.DecodeInt64()
+	case reflect.Uint:
+		*(*uint)(p), err = d.DecodeUint()
+	case reflect.Uint8:
+		*(*uint8)(p), err = d.DecodeUint8()
+	case reflect.Uint16:
+		*(*uint16)(p), err = d.DecodeUint16()
+	case reflect.Uint32:
+		*(*uint32)(p), err = d.DecodeUint32()
+	case reflect.Uint64:
+		*(*uint64)(p), err = d.DecodeUint64()
+	case reflect.Float32:
+		*(*float32)(p), err = d.DecodeFloat32()
+	default:
+		*(*float64)(p), err = d.DecodeFloat64()
+	}
+	return err
+}
@@ -0,0 +1,595 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nurlybekovnt/msgpack/msgpcode"
+)
+
+// NonFinitePolicy defines how NaN and infinite floats, which JSON can't
+// represent, are transcoded.
+type NonFinitePolicy uint8
+
+const (
+	// NonFiniteError fails the transcoding.
+	NonFiniteError NonFinitePolicy = iota
+	// NonFiniteNull writes null.
+	NonFiniteNull
+	// NonFiniteString writes the strings "NaN", "+Inf" and "-Inf".
+	NonFiniteString
+)
+
+// JSONTranscoder converts between msgpack and JSON without decoding into Go
+// values. The zero value is ready to use.
+type JSONTranscoder struct {
+	// Ext appends the JSON representation of an ext value. It must produce a
+	// single valid JSON value. By default ext values are written as
+	// {"type":<id>,"data":"<base64>"}.
+	Ext func(dst []byte, extID int8, data []byte) []byte
+	// Time appends the JSON representation of a timestamp. It must produce a
+	// single valid JSON value. By default timestamps are written as RFC 3339
+	// strings in UTC.
+	Time func(dst []byte, tm time.Time) []byte
+	// NonFinite is the policy for NaN and infinite floats.
+	NonFinite NonFinitePolicy
+
+	// Encoder encodes the values converted by FromJSON. Its SetSortMapKeys,
+	// UseCompactInts and UseCompactFloats options apply.
+	Encoder Encoder
+	// FloatNumbers makes FromJSON encode all numbers as floats. By default
+	// numbers without a fraction or an exponent that fit in an int64 or an
+	// uint64 are encoded as integers.
+	FloatNumbers bool
+	// BinKeys are the map keys whose string values FromJSON decodes from
+	// standard base64 and encodes as bin, reversing ToJSON.
+	BinKeys []string
+}
+
+// DefaultJSONTranscoder is the default JSONTranscoder and is used by ToJSON.
+var DefaultJSONTranscoder = JSONTranscoder{}
+
+// ToJSON appends the JSON representation of the msgpack value src to dst.
+// Strings are escaped and invalid UTF-8 is replaced with U+FFFD, binary data
+// is written as base64 strings and map keys that aren't strings are converted
+// to strings, e.g. 1 to "1". Maps and arrays used as map keys are an error.
+// On error dst is returned unchanged.
+func (t JSONTranscoder) ToJSON(dst, src []byte) ([]byte, error) {
+	d := Decoder{data: src}
+	b, err := t.AppendJSON(dst, &d)
+	if err != nil {
+		return dst, d.wrapError(err)
+	}
+	if d.i < len(src) {
+		return dst, d.wrapError(ErrTrailingData)
+	}
+	return b, nil
+}
+
+func ToJSON(dst, src []byte) ([]byte, error) {
+	return DefaultJSONTranscoder.ToJSON(dst, src)
+}
+
+type jsonFrame struct {
+	n     int // values left, keys and values counted separately for maps
+	isMap bool
+	first bool
+}
+
+// AppendJSON reads the next msgpack value from d and appends its JSON
+// representation to dst, like ToJSON. It returns io.EOF at the end of the
+// input. On error dst is returned unchanged.
+func (t JSONTranscoder) AppendJSON(dst []byte, d *Decoder) ([]byte, error) {
+	start := len(dst)
+	dst, err := t.appendJSON(dst, d)
+	if err != nil {
+		return dst[:start], err
+	}
+	return dst, nil
+}
+
+func (t JSONTranscoder) appendJSON(dst []byte, d *Decoder) ([]byte, error) {
+	// Containers are tracked on an explicit stack instead of recursing, so
+	// deeply nested input can't exhaust the goroutine stack.
+	var stackBuf [16]jsonFrame
+	stack := stackBuf[:0]
+
+	for {
+		isKey := false
+		if top := len(stack) - 1; top >= 0 {
+			f := &stack[top]
+			if f.n == 0 {
+				if f.isMap {
+					dst = append(dst, '}')
+				} else {
+					dst = append(dst, ']')
+				}
+				stack = stack[:top]
+				if top == 0 {
+					return dst, nil
+				}
+				continue
+			}
+
+			isKey = f.isMap && f.n%2 == 0
+			if f.isMap && !isKey {
+				dst = append(dst, ':')
+			} else if !f.first {
+				dst = append(dst, ',')
+			}
+			f.first = false
+			f.n--
+		}
+
+		c, err := d.readCode()
+		if err != nil {
+			if err == io.EOF && len(stack) == 0 {
+				return dst, err
+			}
+			return dst, d.wrapError(err)
+		}
+
+		switch kind := CodeKind(c); {
+		case isKey:
+			dst, err = t.appendKey(dst, d, c)
+		case kind == ArrayKind || kind == MapKind:
+			var n int
+			if kind == MapKind {
+				n, err = d.mapLen(c)
+			} else {
+				n, err = d.arrayLen(c)
+			}
+			if err != nil {
+				return dst, err
+			}
+			if err := d.checkLimit("MaxDepth", d.depth+len(stack)+1, d.limits.MaxDepth); err != nil {
+				return dst, err
+			}
+			if kind == MapKind {
+				dst = append(dst, '{')
+				stack = append(stack, jsonFrame{n: 2 * n, isMap: true, first: true})
+			} else {
+				dst = append(dst, '[')
+				stack = append(stack, jsonFrame{n: n, first: true})
+			}
+			continue
+		default:
+			dst, err = t.appendScalar(dst, d, c)
+		}
+		if err != nil {
+			return dst, err
+		}
+		if len(stack) == 0 {
+			return dst, nil
+		}
+	}
+}
+
+// appendKey appends a map key with code c as a JSON string.
+func (t JSONTranscoder) appendKey(dst []byte, d *Decoder, c byte) ([]byte, error) {
+	switch CodeKind(c) {
+	case StrKind:
+		b, err := d.bytesNoCopy(c)
+		if err != nil {
+			return dst, err
+		}
+		return appendJSONString(dst, b), nil
+	case ArrayKind, MapKind:
+		return dst, d.codeError(c, "scalar map key")
+	}
+
+	start := len(dst)
+	dst, err := t.appendScalar(dst, d, c)
+	if err != nil || dst[start] == '"' {
+		return dst, err
+	}
+	// Quote the JSON text. The escaped copy is appended after the text and
+	// then moved in its place.
+	end := len(dst)
+	dst = appendJSONString(dst, dst[start:end])
+	n := copy(dst[start:], dst[end:])
+	return dst[:start+n], nil
+}
+
+// appendScalar appends the JSON representation of the value with code c,
+// which isn't an array or a map.
+func (t JSONTranscoder) appendScalar(dst []byte, d *Decoder, c byte) ([]byte, error) {
+	switch CodeKind(c) {
+	case NilKind:
+		return append(dst, "null"...), nil
+	case BoolKind:
+		return strconv.AppendBool(dst, c == msgpcode.True), nil
+	case UintKind:
+		n, err := d.uint(c)
+		return strconv.AppendUint(dst, n, 10), err
+	case IntKind:
+		n, err := d.int(c)
+		return strconv.AppendInt(dst, n, 10), err
+	case FloatKind:
+		f, err := d.float64(c)
+		if err != nil {
+			return dst, err
+		}
+		bits := 64
+		if c == msgpcode.Float {
+			bits = 32
+		}
+		return t.appendFloat(dst, d, f, bits)
+	case StrKind:
+		b, err := d.bytesNoCopy(c)
+		return appendJSONString(dst, b), err
+	case BinKind:
+		b, err := d.bytesNoCopy(c)
+		return appendBase64(dst, b), err
+	case ExtKind:
+		extID, extLen, err := d.extHeader(c)
+		if err != nil {
+			return dst, err
+		}
+		if extID == timeExtID {
+			tm, err := d.decodeTimeExt(extLen)
+			if err != nil {
+				return dst, err
+			}
+			if t.Time != nil {
+				return t.Time(dst, tm), nil
+			}
+			dst = append(dst, '"')
+			dst = tm.UTC().AppendFormat(dst, time.RFC3339Nano)
+			return append(dst, '"'), nil
+		}
+
+		b, err := d.readN(extLen)
+		if err != nil {
+			return dst, err
+		}
+		if t.Ext != nil {
+			return t.Ext(dst, extID, b), nil
+		}
+		dst = append(dst, `{"type":`...)
+		dst = strconv.AppendInt(dst, int64(extID), 10)
+		dst = append(dst, `,"data":`...)
+		dst = appendBase64(dst, b)
+		return append(dst, '}'), nil
+	}
+	return dst, d.codeError(c, "")
+}
+
+// appendFloat formats f like encoding/json does.
+func (t JSONTranscoder) appendFloat(dst []byte, d *Decoder, f float64, bits int) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		switch t.NonFinite {
+		case NonFiniteNull:
+			return append(dst, "null"...), nil
+		case NonFiniteString:
+			dst = append(dst, '"')
+			dst = strconv.AppendFloat(dst, f, 'g', -1, bits)
+			return append(dst, '"'), nil
+		}
+		return dst, d.wrapError(fmt.Errorf("msgpack: unsupported float value %v", f))
+	}
+
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) ||
+			bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	dst = strconv.AppendFloat(dst, f, format, -1, bits)
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		n := len(dst)
+		if n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+			dst[n-2] = dst[n-1]
+			dst = dst[:n-1]
+		}
+	}
+	return dst, nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends s as a quoted JSON string.
+func appendJSONString(dst, s []byte) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				dst = append(dst, '\\', c)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRune(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 are valid JSON but break JavaScript string
+		// literals.
+		if r == '\u2028' || r == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
+
+// appendBase64 appends b as a quoted base64 string.
+func appendBase64(dst, b []byte) []byte {
+	dst = append(dst, '"')
+	n := len(dst)
+	dst = append(dst, make([]byte, base64.StdEncoding.EncodedLen(len(b)))...)
+	base64.StdEncoding.Encode(dst[n:], b)
+	return append(dst, '"')
+}
+
+// FromJSON appends the msgpack encoding of the JSON value src to dst. Objects
+// are encoded as maps with string keys, keeping the order and duplicates of
+// the keys unless the Encoder sorts map keys. On error dst is returned
+// unchanged.
+func (t JSONTranscoder) FromJSON(dst, src []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.UseNumber()
+	b, err := t.fromJSON(dst, dec)
+	if err == io.EOF {
+		err = jsonError(io.ErrUnexpectedEOF)
+	}
+	if err != nil {
+		return dst, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return dst, ErrTrailingData
+	}
+	return b, nil
+}
+
+func FromJSON(dst, src []byte) ([]byte, error) {
+	return DefaultJSONTranscoder.FromJSON(dst, src)
+}
+
+// FromJSONReader reads a stream of JSON values from r, such as JSON Lines, and
+// appends them to dst as a sequence of msgpack values like FromJSON. The input
+// is read incrementally. On error the values converted so far are returned
+// with the error.
+func (t JSONTranscoder) FromJSONReader(dst []byte, r io.Reader) ([]byte, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	for {
+		b, err := t.fromJSON(dst, dec)
+		if err == io.EOF {
+			return dst, nil
+		}
+		if err != nil {
+			return dst, err
+		}
+		dst = b
+	}
+}
+
+func FromJSONReader(dst []byte, r io.Reader) ([]byte, error) {
+	return DefaultJSONTranscoder.FromJSONReader(dst, r)
+}
+
+func jsonError(err error) error {
+	return fmt.Errorf("msgpack: invalid JSON: %w", err)
+}
+
+type fromJSONFrame struct {
+	h       ContainerHandle
+	n       int // values appended, keys and values counted separately for maps
+	isMap   bool
+	entries int    // index of the first entry of the map in entries
+	key     string // key of the next map value
+}
+
+// jsonEntry is the location of an encoded key-value pair to be sorted.
+type jsonEntry struct {
+	key        string
+	start, end int
+}
+
+// fromJSON converts the next JSON value of dec. It returns io.EOF at the end
+// of the input.
+func (t JSONTranscoder) fromJSON(dst []byte, dec *json.Decoder) ([]byte, error) {
+	sortKeys := t.Encoder.flags&sortMapKeysFlag != 0
+	var (
+		stackBuf [16]fromJSONFrame
+		stack    = stackBuf[:0]
+		entries  []jsonEntry
+	)
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				if len(stack) == 0 {
+					return dst, err
+				}
+				err = io.ErrUnexpectedEOF
+			}
+			return dst, jsonError(err)
+		}
+
+		var top *fromJSONFrame
+		if len(stack) > 0 {
+			top = &stack[len(stack)-1]
+		}
+
+		if tok == json.Delim('}') || tok == json.Delim(']') {
+			// Headers are always compacted, however long the contents, so
+			// the output matches Encoder.Append.
+			if top.isMap {
+				if sortKeys {
+					dst = sortJSONEntries(dst, entries[top.entries:])
+					entries = entries[:top.entries]
+				}
+				dst = t.Encoder.finish(dst, top.h, top.n/2, math.MaxInt)
+			} else {
+				dst = t.Encoder.finish(dst, top.h, top.n, math.MaxInt)
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return dst, nil
+			}
+			continue
+		}
+
+		var binKey string
+		isBin := false
+		if top != nil {
+			if top.isMap && top.n%2 == 0 {
+				// Object keys are always strings.
+				key := tok.(string)
+				if sortKeys {
+					if n := len(entries); n > top.entries {
+						entries[n-1].end = len(dst)
+					}
+					entries = append(entries, jsonEntry{key: key, start: len(dst)})
+				}
+				top.key = key
+				top.n++
+				dst = t.Encoder.AppendString(dst, key)
+				continue
+			}
+			if top.isMap {
+				binKey = top.key
+				isBin = t.isBinKey(binKey)
+			}
+			top.n++
+		}
+
+		switch tok := tok.(type) {
+		case json.Delim:
+			var h ContainerHandle
+			if tok == '{' {
+				dst, h = t.Encoder.AppendMapStart(dst)
+				stack = append(stack, fromJSONFrame{h: h, isMap: true, entries: len(entries)})
+			} else {
+				dst, h = t.Encoder.AppendArrayStart(dst)
+				stack = append(stack, fromJSONFrame{h: h})
+			}
+			continue
+		case nil:
+			dst = t.Encoder.AppendNil(dst)
+		case bool:
+			dst = t.Encoder.AppendBool(dst, tok)
+		case json.Number:
+			dst = t.appendNumber(dst, string(tok))
+		case string:
+			if isBin {
+				dst, err = t.appendBase64Bin(dst, tok)
+				if err != nil {
+					return dst, fmt.Errorf("msgpack: invalid base64 value of key %q: %w", binKey, err)
+				}
+			} else {
+				dst = t.Encoder.AppendString(dst, tok)
+			}
+		}
+
+		if len(stack) == 0 {
+			return dst, nil
+		}
+	}
+}
+
+func (t JSONTranscoder) isBinKey(key string) bool {
+	for _, k := range t.BinKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// appendNumber appends the JSON number s, which is syntactically valid.
+func (t JSONTranscoder) appendNumber(dst []byte, s string) []byte {
+	if !t.FloatNumbers && strings.IndexAny(s, ".eE") < 0 {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return t.Encoder.appendInt64Cond(dst, n)
+		}
+		if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return t.Encoder.appendUint64Cond(dst, n)
+		}
+	}
+	// Numbers out of the float64 range are encoded as infinity.
+	f, _ := strconv.ParseFloat(s, 64)
+	return t.Encoder.AppendFloat64(dst, f)
+}
+
+func (t JSONTranscoder) appendBase64Bin(dst []byte, s string) ([]byte, error) {
+	enc := base64.StdEncoding
+	start := len(dst)
+	maxLen := enc.DecodedLen(len(s))
+	dst = t.Encoder.AppendBytesLen(dst, maxLen)
+	body := len(dst)
+	dst = append(dst, make([]byte, maxLen)...)
+	n, err := enc.Decode(dst[body:], []byte(s))
+	if err != nil {
+		return dst[:start], err
+	}
+	if n == maxLen {
+		return dst, nil
+	}
+	// Padding made the header too long; encode it again.
+	dst = t.Encoder.AppendBytesLen(dst[:start], n)
+	return append(dst, dst[body:body+n]...), nil
+}
+
+// sortJSONEntries sorts the encoded map entries at the end of dst by key. The
+// end of the last entry is the end of dst.
+func sortJSONEntries(dst []byte, entries []jsonEntry) []byte {
+	if len(entries) < 2 {
+		return dst
+	}
+	entries[len(entries)-1].end = len(dst)
+	if sort.SliceIsSorted(entries, func(i, j int) bool { return entries[i].key < entries[j].key }) {
+		return dst
+	}
+
+	start := entries[0].start
+	tmp := append([]byte(nil), dst[start:]...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+	w := start
+	for _, e := range entries {
+		w += copy(dst[w:], tmp[e.start-start:e.end-start])
+	}
+	return dst
+}
@@ -0,0 +1,224 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nurlybekovnt/msgpack"
+)
+
+func TestToJSON(t *testing.T) {
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	tests := []struct {
+		name string
+		t    msgpack.JSONTranscoder
+		data []byte
+		want string
+	}{
+		{"nil", msgpack.JSONTranscoder{}, msgpack.AppendNil(nil), `null`},
+		{"bool", msgpack.JSONTranscoder{}, msgpack.AppendBool(nil, true), `true`},
+		{"int", msgpack.JSONTranscoder{}, msgpack.AppendInt64(nil, -42), `-42`},
+		{"uint", msgpack.JSONTranscoder{}, msgpack.AppendUint64(nil, math.MaxUint64), `18446744073709551615`},
+		{"float", msgpack.JSONTranscoder{}, msgpack.AppendFloat64(nil, 1.5), `1.5`},
+		{"float exp", msgpack.JSONTranscoder{}, msgpack.AppendFloat64(nil, 1e-9), `1e-9`},
+		{"float32", msgpack.JSONTranscoder{}, msgpack.AppendFloat32(nil, 0.1), `0.1`},
+		{"string", msgpack.JSONTranscoder{}, msgpack.AppendString(nil, "a\"\n<\u2028"), `"a\"\n<\u2028"`},
+		{"invalid utf8", msgpack.JSONTranscoder{}, msgpack.AppendString(nil, "a\xff"), `"a\ufffd"`},
+		{"bin", msgpack.JSONTranscoder{}, msgpack.AppendBytes(nil, []byte{1, 2, 3}), `"AQID"`},
+		{"time", msgpack.JSONTranscoder{}, msgpack.AppendTime(nil, tm), `"2024-01-02T03:04:05.000000006Z"`},
+		{"ext", msgpack.JSONTranscoder{}, msgpack.Append(nil, msgpack.RawExt{Type: 5, Data: []byte{1}}), `{"type":5,"data":"AQ=="}`},
+		{
+			"custom ext and time",
+			msgpack.JSONTranscoder{
+				Ext:  func(dst []byte, extID int8, data []byte) []byte { return append(dst, "1"...) },
+				Time: func(dst []byte, tm time.Time) []byte { return append(dst, "2"...) },
+			},
+			msgpack.Append(nil, []interface{}{msgpack.RawExt{Type: 5}, tm}),
+			`[1,2]`,
+		},
+		{"nan null", msgpack.JSONTranscoder{NonFinite: msgpack.NonFiniteNull}, msgpack.AppendFloat64(nil, math.NaN()), `null`},
+		{"inf string", msgpack.JSONTranscoder{NonFinite: msgpack.NonFiniteString}, msgpack.AppendFloat64(nil, math.Inf(-1)), `"-Inf"`},
+		{
+			"nested",
+			msgpack.JSONTranscoder{},
+			msgpack.AppendMapSorted(nil, map[string]interface{}{"a": []interface{}{1, "x", nil}, "b": map[string]interface{}{}}),
+			`{"a":[1,"x",null],"b":{}}`,
+		},
+		{"non-string keys", msgpack.JSONTranscoder{}, []byte{0x83, 0x01, 0xc0, 0xc3, 0xc0, 0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0, 0xc0}, `{"1":null,"true":null,"1.5":null}`},
+		{"escaped key", msgpack.JSONTranscoder{}, []byte{0x81, 0xa1, '"', 0x01}, `{"\"":1}`},
+		{"deep", msgpack.JSONTranscoder{}, append(bytes.Repeat([]byte{0x91}, 10000), 0xc0), strings.Repeat("[", 10000) + "null" + strings.Repeat("]", 10000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.t.ToJSON([]byte("prefix "), tt.data)
+			if err != nil {
+				t.Fatalf("ToJSON() error = %v", err)
+			}
+			if want := "prefix " + tt.want; string(got) != want {
+				t.Fatalf("ToJSON() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestToJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"empty", nil, msgpack.ErrTruncated},
+		{"truncated", msgpack.Append(nil, []interface{}{1, "abc"})[:4], msgpack.ErrTruncated},
+		{"trailing", []byte{0xc0, 0xc0}, msgpack.ErrTrailingData},
+		{"array key", []byte{0x81, 0x90, 0x01}, msgpack.ErrUnexpectedCode},
+		{"invalid code", []byte{0x91, 0xc1}, msgpack.ErrUnexpectedCode},
+		{"nan", msgpack.AppendFloat64(nil, math.NaN()), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := msgpack.ToJSON([]byte("prefix"), tt.data)
+			var de *msgpack.DecodeError
+			if !errors.As(err, &de) {
+				t.Fatalf("ToJSON() error = %v (%T), want *DecodeError", err, err)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("ToJSON() error = %v, want %v", err, tt.err)
+			}
+			if string(got) != "prefix" {
+				t.Fatalf("ToJSON() = %q on error, want dst unchanged", got)
+			}
+		})
+	}
+}
+
+func TestAppendJSONStream(t *testing.T) {
+	data := msgpack.AppendMulti(nil, 1, "a", []int{2})
+	d := msgpack.NewReaderDecoder(bytes.NewReader(data))
+	var got []string
+	for {
+		b, err := msgpack.DefaultJSONTranscoder.AppendJSON(nil, d)
+		if err != nil {
+			if err != io.EOF {
+				t.Fatal(err)
+			}
+			break
+		}
+		got = append(got, string(b))
+	}
+	if want := `1 "a" [2]`; strings.Join(got, " ") != want {
+		t.Fatalf("AppendJSON() = %q, want %s", got, want)
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	var (
+		sorted      msgpack.JSONTranscoder
+		floats      = msgpack.JSONTranscoder{FloatNumbers: true}
+		compactInts msgpack.JSONTranscoder
+		bin         = msgpack.JSONTranscoder{BinKeys: []string{"data"}}
+	)
+	sorted.Encoder.SetSortMapKeys(true)
+	compactInts.Encoder.UseCompactInts(true)
+
+	long := strings.Repeat("x", 2000)
+	manyKeys := make(map[string]interface{})
+	for i := 0; i < 200; i++ {
+		manyKeys[strings.Repeat("k", i+1)] = long[:i]
+	}
+	manyKeysJSON, err := msgpack.ToJSON(nil, msgpack.AppendMapSorted(nil, manyKeys))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// m encodes a map with the keys and values in order.
+	m := func(kv ...interface{}) msgpack.RawMessage {
+		b := msgpack.AppendMapLen(nil, len(kv)/2)
+		return msgpack.AppendMulti(b, kv...)
+	}
+
+	tests := []struct {
+		name string
+		t    msgpack.JSONTranscoder
+		src  string
+		want []byte
+	}{
+		{"null", msgpack.JSONTranscoder{}, `null`, msgpack.AppendNil(nil)},
+		{"int", msgpack.JSONTranscoder{}, `-7`, msgpack.Append(nil, int64(-7))},
+		{"uint", msgpack.JSONTranscoder{}, `18446744073709551615`, msgpack.Append(nil, uint64(math.MaxUint64))},
+		{"float", msgpack.JSONTranscoder{}, `1.5`, msgpack.AppendFloat64(nil, 1.5)},
+		{"exponent", msgpack.JSONTranscoder{}, `1e3`, msgpack.AppendFloat64(nil, 1000)},
+		{"out of range", msgpack.JSONTranscoder{}, `1e400`, msgpack.AppendFloat64(nil, math.Inf(1))},
+		{"float numbers", floats, `1`, msgpack.AppendFloat64(nil, 1)},
+		{"compact ints", compactInts, `1`, []byte{0x01}},
+		{"string", msgpack.JSONTranscoder{}, `"aé"`, msgpack.AppendString(nil, "aé")},
+		{"object order", msgpack.JSONTranscoder{}, `{"b":1,"a":[true,null,"x"]}`, m("b", int64(1), "a", []interface{}{true, nil, "x"})},
+		{"sorted", sorted, `{"b":1,"a":{"d":2,"c":3}}`, m("a", m("c", int64(3), "d", int64(2)), "b", int64(1))},
+		{"duplicate keys", msgpack.JSONTranscoder{}, `{"a":1,"a":2}`, m("a", int64(1), "a", int64(2))},
+		{"bin keys", bin, `{"data":"AQID","s":"AQID","n":{"data":""}}`, m("data", []byte{1, 2, 3}, "s", "AQID", "n", m("data", []byte{}))},
+		{"long string", msgpack.JSONTranscoder{}, `["` + long + `"]`, msgpack.Append(nil, []interface{}{long})},
+		{"many keys", sorted, string(manyKeysJSON), msgpack.AppendMapSorted(nil, manyKeys)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix := msgpack.AppendString(nil, "prefix")
+			got, err := tt.t.FromJSON(bytes.Clone(prefix), []byte(tt.src))
+			if err != nil {
+				t.Fatalf("FromJSON() error = %v", err)
+			}
+			if want := append(prefix, tt.want...); !bytes.Equal(got, want) {
+				t.Fatalf("FromJSON() = %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	bin := msgpack.JSONTranscoder{BinKeys: []string{"data"}}
+	tests := []struct {
+		name string
+		t    msgpack.JSONTranscoder
+		src  string
+		err  string // prefix of the error message
+	}{
+		{"empty", msgpack.JSONTranscoder{}, ``, "msgpack: invalid JSON: unexpected EOF"},
+		{"truncated", msgpack.JSONTranscoder{}, `[1,`, "msgpack: invalid JSON: unexpected EOF"},
+		{"invalid", msgpack.JSONTranscoder{}, `{"a":}`, "msgpack: invalid JSON: "},
+		{"trailing", msgpack.JSONTranscoder{}, `1 2`, msgpack.ErrTrailingData.Error()},
+		{"base64", bin, `{"data":"!"}`, `msgpack: invalid base64 value of key "data": illegal base64 data at input byte 0`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.t.FromJSON([]byte("prefix"), []byte(tt.src))
+			if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+				t.Fatalf("FromJSON() error = %v, want %s", err, tt.err)
+			}
+			if string(got) != "prefix" {
+				t.Fatalf("FromJSON() = %q on error, want dst unchanged", got)
+			}
+		})
+	}
+}
+
+func TestFromJSONReader(t *testing.T) {
+	got, err := msgpack.FromJSONReader(nil, strings.NewReader("1\n[\"a\"]\n{}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := msgpack.AppendMulti(nil, int64(1), []interface{}{"a"}, map[string]interface{}{})
+	if !bytes.Equal(got, want) {
+		t.Fatalf("FromJSONReader() = %x, want %x", got, want)
+	}
+
+	got, err = msgpack.FromJSONReader(nil, strings.NewReader(`1 [2`))
+	if err == nil {
+		t.Fatal("FromJSONReader() of truncated input succeeded")
+	}
+	if want := msgpack.Append(nil, int64(1)); !bytes.Equal(got, want) {
+		t.Fatalf("FromJSONReader() = %x on error, want the complete values %x", got, want)
+	}
+}
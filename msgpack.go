@@ -1,12 +1,85 @@
 package msgpack
 
-import "fmt"
+import (
+	"github.com/nurlybekovnt/msgpack/msgpcode"
+)
 
-type unexpectedCodeError struct {
-	code byte
-	hint string
+// Marshaler is the interface implemented by types that can append their own
+// msgpack encoding to dst. The result must be exactly one msgpack value.
+type Marshaler interface {
+	AppendMsgpack(dst []byte) []byte
 }
 
-func (err unexpectedCodeError) Error() string {
-	return fmt.Sprintf("msgpack: unexpected code=%x decoding %s", err.code, err.hint)
+// Unmarshaler is the interface implemented by types that can decode a msgpack
+// representation of themselves. DecodeMsgpack must consume exactly one value.
+type Unmarshaler interface {
+	DecodeMsgpack(d *Decoder) error
+}
+
+// Kind is the type family of a msgpack value.
+type Kind uint8
+
+const (
+	InvalidKind Kind = iota
+	NilKind
+	BoolKind
+	UintKind
+	IntKind
+	FloatKind
+	StrKind
+	BinKind
+	ArrayKind
+	MapKind
+	ExtKind
+)
+
+var kindNames = [...]string{
+	InvalidKind: "invalid",
+	NilKind:     "nil",
+	BoolKind:    "bool",
+	UintKind:    "uint",
+	IntKind:     "int",
+	FloatKind:   "float",
+	StrKind:     "str",
+	BinKind:     "bin",
+	ArrayKind:   "array",
+	MapKind:     "map",
+	ExtKind:     "ext",
+}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "invalid"
+}
+
+// CodeKind returns the kind of the values starting with code c. Positive
+// fixnums are reported as UintKind and negative ones as IntKind.
+func CodeKind(c byte) Kind {
+	switch {
+	case c == msgpcode.Nil:
+		return NilKind
+	case c == msgpcode.False, c == msgpcode.True:
+		return BoolKind
+	case c <= msgpcode.PosFixedNumHigh,
+		c == msgpcode.Uint8, c == msgpcode.Uint16, c == msgpcode.Uint32, c == msgpcode.Uint64:
+		return UintKind
+	case c >= msgpcode.NegFixedNumLow,
+		c == msgpcode.Int8, c == msgpcode.Int16, c == msgpcode.Int32, c == msgpcode.Int64:
+		return IntKind
+	case c == msgpcode.Float, c == msgpcode.Double:
+		return FloatKind
+	case msgpcode.IsString(c):
+		return StrKind
+	case msgpcode.IsBin(c):
+		return BinKind
+	case msgpcode.IsFixedArray(c), c == msgpcode.Array16, c == msgpcode.Array32:
+		return ArrayKind
+	case msgpcode.IsFixedMap(c), c == msgpcode.Map16, c == msgpcode.Map32:
+		return MapKind
+	case msgpcode.IsExt(c):
+		return ExtKind
+	}
+	return InvalidKind
 }
@@ -0,0 +1,244 @@
+package msgpack
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Path is a compiled selector of nested values. It is evaluated directly on
+// encoded data: only the map keys and array elements on the way to the
+// selected values are looked at, everything else is skipped without decoding.
+// A Path is safe for concurrent use.
+type Path struct {
+	expr string
+	segs []pathSeg
+}
+
+type pathSeg struct {
+	key      string
+	hasKey   bool // selects the map value for key
+	index    int  // selects the array element at index if not negative
+	wildcard bool // selects all map values and array elements
+}
+
+// CompilePath parses a path expression. Two syntaxes are supported:
+//
+//   - Dot notation: map keys separated by dots and array indices in brackets,
+//     e.g. meta.tenant.id or items[2].sku. A * key or index selects all map
+//     values or array elements, e.g. items[*].sku. Keys containing dots or
+//     brackets can be given as quoted strings in brackets, e.g. ["a.b"].
+//   - JSON Pointer (RFC 6901), e.g. /items/2/sku. Expressions starting with a
+//     slash and the empty expression, which selects the whole value, are
+//     parsed as JSON Pointers.
+func CompilePath(expr string) (*Path, error) {
+	var (
+		segs []pathSeg
+		err  error
+	)
+	if expr == "" || expr[0] == '/' {
+		segs, err = parsePointer(expr)
+	} else {
+		segs, err = parseDotPath(expr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("msgpack: invalid path %q: %s", expr, err)
+	}
+	return &Path{expr: expr, segs: segs}, nil
+}
+
+// MustCompilePath is like CompilePath but panics if the expression is invalid.
+func MustCompilePath(expr string) *Path {
+	p, err := CompilePath(expr)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (p *Path) String() string {
+	return p.expr
+}
+
+func parsePointer(expr string) ([]pathSeg, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	tokens := strings.Split(expr[1:], "/")
+	segs := make([]pathSeg, 0, len(tokens))
+	for _, tok := range tokens {
+		for i := 0; i < len(tok); i++ {
+			if tok[i] == '~' && (i+1 == len(tok) || (tok[i+1] != '0' && tok[i+1] != '1')) {
+				return nil, fmt.Errorf("invalid escape in %q", tok)
+			}
+		}
+		tok = strings.ReplaceAll(tok, "~1", "/")
+		tok = strings.ReplaceAll(tok, "~0", "~")
+
+		seg := pathSeg{key: tok, hasKey: true, index: -1}
+		if isArrayIndex(tok) {
+			seg.index, _ = strconv.Atoi(tok)
+		}
+		segs = append(segs, seg)
+	}
+	return segs, nil
+}
+
+// isArrayIndex reports whether s is a decimal number without leading zeros.
+func isArrayIndex(s string) bool {
+	if s == "" || len(s) > 9 || (s[0] == '0' && len(s) > 1) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func parseDotPath(expr string) ([]pathSeg, error) {
+	var segs []pathSeg
+	for i := 0; i < len(expr); {
+		if expr[i] == '[' {
+			seg, n, err := parseBracket(expr[i:])
+			if err != nil {
+				return nil, err
+			}
+			segs = append(segs, seg)
+			i += n
+			continue
+		}
+
+		if len(segs) > 0 {
+			if expr[i] != '.' {
+				return nil, fmt.Errorf("unexpected %q at %d", expr[i], i)
+			}
+			i++
+		}
+		j := i
+		for j < len(expr) && expr[j] != '.' && expr[j] != '[' {
+			j++
+		}
+		if j == i {
+			return nil, fmt.Errorf("empty key at %d", i)
+		}
+		if key := expr[i:j]; key == "*" {
+			segs = append(segs, pathSeg{index: -1, wildcard: true})
+		} else {
+			segs = append(segs, pathSeg{key: key, hasKey: true, index: -1})
+		}
+		i = j
+	}
+	return segs, nil
+}
+
+// parseBracket parses a [index], [*] or ["key"] segment at the start of s and
+// returns it with its length.
+func parseBracket(s string) (pathSeg, int, error) {
+	if len(s) > 1 && s[1] == '"' {
+		quoted, err := strconv.QuotedPrefix(s[1:])
+		if err != nil {
+			return pathSeg{}, 0, fmt.Errorf("invalid quoted key in %s", s)
+		}
+		n := 1 + len(quoted)
+		if n >= len(s) || s[n] != ']' {
+			return pathSeg{}, 0, fmt.Errorf("missing ] in %s", s)
+		}
+		key, _ := strconv.Unquote(quoted)
+		return pathSeg{key: key, hasKey: true, index: -1}, n + 1, nil
+	}
+
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return pathSeg{}, 0, fmt.Errorf("missing ] in %s", s)
+	}
+	switch inner := s[1:end]; {
+	case inner == "*":
+		return pathSeg{index: -1, wildcard: true}, end + 1, nil
+	case isArrayIndex(inner):
+		index, _ := strconv.Atoi(inner)
+		return pathSeg{index: index}, end + 1, nil
+	default:
+		return pathSeg{}, 0, fmt.Errorf("invalid index %q", inner)
+	}
+}
+
+// Find returns the first value selected by p in the msgpack value b. If no
+// value is selected, the returned Value holds an error matching ErrNotFound.
+func (p *Path) Find(b []byte) Value {
+	var found Value
+	err := p.Each(b, func(v Value) bool {
+		found = v
+		return false
+	})
+	if err != nil {
+		return errValue(err)
+	}
+	if found.b == nil {
+		return errValue(fmt.Errorf("%w: %s", ErrNotFound, p.expr))
+	}
+	return found
+}
+
+// Each calls fn for every value selected by p in the msgpack value b, in
+// encoding order, until fn returns false. Values that don't have the selected
+// keys or indices, or that aren't maps or arrays, are silently ignored. An
+// error is only returned for malformed input.
+func (p *Path) Each(b []byte, fn func(v Value) bool) error {
+	_, err := each(NewValue(b), p.segs, fn)
+	return err
+}
+
+// each calls fn for the values selected by segs in v. It returns false when
+// fn stopped the iteration.
+func each(v Value, segs []pathSeg, fn func(v Value) bool) (bool, error) {
+	if len(segs) == 0 {
+		return fn(v), nil
+	}
+	seg := &segs[0]
+
+	switch v.Kind() {
+	case MapKind:
+		if !seg.hasKey && !seg.wildcard {
+			return true, nil
+		}
+	case ArrayKind:
+		if seg.index < 0 && !seg.wildcard {
+			return true, nil
+		}
+		if !seg.wildcard {
+			n, err := v.Len()
+			if err != nil || seg.index >= n {
+				return true, err
+			}
+			elem := v.Index(seg.index)
+			if err := elem.Err(); err != nil {
+				return false, err
+			}
+			return each(elem, segs[1:], fn)
+		}
+	default:
+		return true, nil
+	}
+
+	more := true
+	var err error
+	iterErr := v.Iterate(func(key, value Value) bool {
+		if !seg.wildcard {
+			k, kerr := key.StrBytes()
+			if kerr != nil || string(k) != seg.key {
+				return true
+			}
+			// The first occurrence of a key wins, like in Value.Get.
+			more, err = each(value, segs[1:], fn)
+			return false
+		}
+		more, err = each(value, segs[1:], fn)
+		return more && err == nil
+	})
+	if iterErr != nil {
+		return false, iterErr
+	}
+	return more, err
+}
@@ -0,0 +1,82 @@
+package msgpack_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/nurlybekovnt/msgpack"
+)
+
+func TestPathFind(t *testing.T) {
+	data := msgpack.AppendMapSorted(nil, map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"sku": "a"},
+			map[string]interface{}{"sku": "b"},
+		},
+		"a.b":  "dotted",
+		"meta": map[string]interface{}{"id": 7},
+	})
+	// {nil: 1, "": 2}
+	nilKey := []byte{0x82, 0xc0, 0x01, 0xa0, 0x02}
+
+	tests := []struct {
+		expr string
+		data []byte
+		want interface{}
+		err  error
+	}{
+		{"meta.id", data, int8(7), nil},
+		{"/meta/id", data, int8(7), nil},
+		{"items[1].sku", data, "b", nil},
+		{"/items/0/sku", data, "a", nil},
+		{`["a.b"]`, data, "dotted", nil},
+		{"items[2].sku", data, nil, msgpack.ErrNotFound},
+		{"meta.id.x", data, nil, msgpack.ErrNotFound},
+		{`[""]`, nilKey, int8(2), nil},
+		{"/", nilKey, int8(2), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			v := msgpack.MustCompilePath(tt.expr).Find(tt.data)
+			if err := v.Err(); !errors.Is(err, tt.err) || (tt.err == nil && err != nil) {
+				t.Fatalf("Find() error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			var got interface{}
+			if err := v.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("Find() = %v (%T), want %v (%T)", got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathEach(t *testing.T) {
+	data := msgpack.Append(nil, []interface{}{
+		map[string]interface{}{"sku": "a"},
+		map[string]interface{}{"id": 1},
+		"not a map",
+		map[string]interface{}{"sku": "c"},
+	})
+
+	var got []string
+	err := msgpack.MustCompilePath("[*].sku").Each(data, func(v msgpack.Value) bool {
+		s, err := v.Str()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, s)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Each() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,71 @@
+package msgpack
+
+// RawMessage is a raw encoded msgpack value. It can be used to delay decoding
+// of a value or to forward it untouched.
+type RawMessage []byte
+
+var _ Marshaler = RawMessage(nil)
+var _ Unmarshaler = (*RawMessage)(nil)
+
+// AppendMsgpack implements Marshaler. It appends m verbatim, or nil if m is
+// empty, which isn't a valid encoded value.
+func (m RawMessage) AppendMsgpack(dst []byte) []byte {
+	if len(m) == 0 {
+		return AppendNil(dst)
+	}
+	return append(dst, m...)
+}
+
+// DecodeMsgpack implements Unmarshaler.
+func (m *RawMessage) DecodeMsgpack(d *Decoder) error {
+	raw, err := d.DecodeRaw()
+	if err != nil {
+		return err
+	}
+	*m = raw
+	return nil
+}
+
+// DecodeRaw decodes the next value and returns its exact encoded bytes.
+//
+// Enabling the UnsafeDecoding flag causes the returned message to reference
+// the underlying decoding byte slice instead of a copy.
+func (d *Decoder) DecodeRaw() (RawMessage, error) {
+	start, err := d.skipMarked()
+	if err != nil {
+		return nil, err
+	}
+	b := d.data[start:d.i]
+
+	if d.unsafeDecoding() {
+		return b, nil
+	}
+	if err := d.alloc(len(b)); err != nil {
+		return nil, err
+	}
+	raw := make(RawMessage, len(b))
+	copy(raw, b)
+	return raw, nil
+}
+
+// skipMarked skips the next value and returns the index at which it starts in
+// d.data. Reader-backed decoders keep the whole value in the buffer.
+func (d *Decoder) skipMarked() (int, error) {
+	if d.r == nil {
+		start := d.i
+		return start, d.Skip()
+	}
+
+	marked := d.mark >= 0
+	if !marked {
+		d.mark = d.i
+	}
+	off := d.i - d.mark
+
+	err := d.Skip()
+	start := d.mark + off
+	if !marked {
+		d.mark = -1
+	}
+	return start, err
+}
@@ -0,0 +1,46 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nurlybekovnt/msgpack"
+)
+
+func TestRawMessageEncode(t *testing.T) {
+	nilValue := msgpack.AppendNil(nil)
+	tests := []struct {
+		name string
+		raw  msgpack.RawMessage
+		want []byte
+	}{
+		{"nil", nil, nilValue},
+		{"empty", msgpack.RawMessage{}, nilValue},
+		{"value", msgpack.RawMessage(msgpack.AppendString(nil, "x")), msgpack.AppendString(nil, "x")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := msgpack.Append(nil, tt.raw); !bytes.Equal(got, tt.want) {
+				t.Errorf("Append() = %x, want %x", got, tt.want)
+			}
+
+			b := msgpack.NewBuilder(nil)
+			b.AppendRaw(tt.raw)
+			if got := b.Bytes(); !bytes.Equal(got, tt.want) {
+				t.Errorf("Builder.AppendRaw() = %x, want %x", got, tt.want)
+			}
+
+			var buf bytes.Buffer
+			s := msgpack.NewStreamEncoder(&buf, 0)
+			if err := s.EncodeRaw(tt.raw); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.Bytes(); !bytes.Equal(got, tt.want) {
+				t.Errorf("StreamEncoder.EncodeRaw() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
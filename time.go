@@ -44,6 +44,10 @@ func (d *Decoder) DecodeTime() (time.Time, error) {
 		return time.Time{}, err
 	}
 
+	if d.strict() && !msgpcode.IsExt(c) {
+		return time.Time{}, d.codeError(c, "timestamp ext")
+	}
+
 	// Legacy format.
 	if c == msgpcode.FixedArrayLow|2 {
 		sec, err := d.DecodeInt64()
@@ -74,9 +78,13 @@ func (d *Decoder) DecodeTime() (time.Time, error) {
 
 	// NodeJS seems to use extID 13.
 	if extID != timeExtID && extID != 13 {
-		return time.Time{}, fmt.Errorf("msgpack: invalid time ext id=%d", extID)
+		return time.Time{}, d.wrapError(fmt.Errorf("msgpack: invalid time ext id=%d", extID))
 	}
 
+	return d.decodeTimeExt(extLen)
+}
+
+func (d *Decoder) decodeTimeExt(extLen int) (time.Time, error) {
 	tm, err := d.decodeTime(extLen)
 	if err != nil {
 		return tm, err
@@ -109,7 +117,7 @@ func (d *Decoder) decodeTime(extLen int) (time.Time, error) {
 		sec := binary.BigEndian.Uint64(b[4:])
 		return time.Unix(int64(sec), int64(nsec)), nil
 	default:
-		err = fmt.Errorf("msgpack: invalid ext len=%d decoding time", extLen)
+		err = d.wrapError(fmt.Errorf("msgpack: invalid ext len=%d decoding time", extLen))
 		return time.Time{}, err
 	}
 }
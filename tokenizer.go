@@ -0,0 +1,215 @@
+package msgpack
+
+import (
+	"io"
+	"time"
+
+	"github.com/nurlybekovnt/msgpack/msgpcode"
+)
+
+// TokenKind is the kind of a Token.
+type TokenKind uint8
+
+const (
+	NilToken TokenKind = iota
+	BoolToken
+	IntToken
+	UintToken
+	FloatToken
+	StrToken
+	BinToken
+	ExtToken
+	TimestampToken
+	ArrayStartToken
+	ArrayEndToken
+	MapStartToken
+	MapEndToken
+)
+
+var tokenKindNames = [...]string{
+	NilToken:        "Nil",
+	BoolToken:       "Bool",
+	IntToken:        "Int",
+	UintToken:       "Uint",
+	FloatToken:      "Float",
+	StrToken:        "Str",
+	BinToken:        "Bin",
+	ExtToken:        "Ext",
+	TimestampToken:  "Timestamp",
+	ArrayStartToken: "ArrayStart",
+	ArrayEndToken:   "ArrayEnd",
+	MapStartToken:   "MapStart",
+	MapEndToken:     "MapEnd",
+}
+
+func (k TokenKind) String() string {
+	if int(k) < len(tokenKindNames) {
+		return tokenKindNames[k]
+	}
+	return "Invalid"
+}
+
+// Token is a single element of a msgpack stream returned by Tokenizer. Only
+// the field matching Kind is set.
+type Token struct {
+	Kind TokenKind
+	// Len is the number of elements of ArrayStart and the number of key-value
+	// pairs of MapStart.
+	Len   int
+	Bool  bool
+	Int   int64
+	Uint  uint64
+	Float float64
+	// Bytes is the data of Str, Bin and Ext. It references the decoder input
+	// and, for reader-backed decoders, is only valid until the next read.
+	Bytes   []byte
+	ExtType int8
+	Time    time.Time
+}
+
+// Tokenizer reads a msgpack stream as a flat sequence of tokens, akin to
+// encoding/json.Decoder.Token. Arrays and maps are reported as a Start token
+// followed by their elements, keys and values alternating, and a synthesized
+// End token, so arbitrary messages can be walked without recursion. Memory use
+// only depends on the nesting depth, which is bounded by the MaxDepth limit of
+// the Decoder.
+type Tokenizer struct {
+	d     *Decoder
+	base  int // nesting depth of d when the Tokenizer was set up
+	stack []tokenFrame
+	key   bool
+}
+
+type tokenFrame struct {
+	n     int // values left, keys and values counted separately for maps
+	isMap bool
+}
+
+// NewTokenizer returns a Tokenizer reading from d.
+func NewTokenizer(d *Decoder) *Tokenizer {
+	return &Tokenizer{d: d, base: d.depth}
+}
+
+// Reset resets the Tokenizer to read from d. The containers left open in the
+// previous decoder are abandoned.
+func (t *Tokenizer) Reset(d *Decoder) {
+	t.unwind()
+	t.d = d
+	t.base = d.depth
+	t.key = false
+}
+
+// unwind abandons the open containers, restoring the nesting depth of the
+// decoder.
+func (t *Tokenizer) unwind() {
+	if len(t.stack) > 0 {
+		t.d.depth = t.base
+		t.stack = t.stack[:0]
+	}
+}
+
+// Depth returns the number of arrays and maps enclosing the next token.
+func (t *Tokenizer) Depth() int {
+	return len(t.stack)
+}
+
+// IsKey reports whether the last token returned by Token is a map key.
+func (t *Tokenizer) IsKey() bool {
+	return t.key
+}
+
+// Token returns the next token. At the end of the input it returns io.EOF if
+// all containers are complete. After any other error the open containers are
+// abandoned and Depth returns 0.
+func (t *Tokenizer) Token() (Token, error) {
+	tok, err := t.next()
+	if err != nil {
+		t.unwind()
+	}
+	return tok, err
+}
+
+func (t *Tokenizer) next() (Token, error) {
+	t.key = false
+	if top := len(t.stack) - 1; top >= 0 {
+		f := &t.stack[top]
+		if f.n == 0 {
+			t.stack = t.stack[:top]
+			t.d.leave()
+			if f.isMap {
+				return Token{Kind: MapEndToken}, nil
+			}
+			return Token{Kind: ArrayEndToken}, nil
+		}
+		t.key = f.isMap && f.n%2 == 0
+		f.n--
+	}
+
+	c, err := t.d.readCode()
+	if err != nil {
+		if err == io.EOF && len(t.stack) == 0 {
+			return Token{}, err
+		}
+		return Token{}, t.d.wrapError(err)
+	}
+	return t.token(c)
+}
+
+func (t *Tokenizer) token(c byte) (Token, error) {
+	d := t.d
+	switch kind := CodeKind(c); kind {
+	case NilKind:
+		return Token{Kind: NilToken}, nil
+	case BoolKind:
+		return Token{Kind: BoolToken, Bool: c == msgpcode.True}, nil
+	case UintKind:
+		n, err := d.uint(c)
+		return Token{Kind: UintToken, Uint: n}, err
+	case IntKind:
+		n, err := d.int(c)
+		return Token{Kind: IntToken, Int: n}, err
+	case FloatKind:
+		f, err := d.float64(c)
+		return Token{Kind: FloatToken, Float: f}, err
+	case StrKind, BinKind:
+		b, err := d.bytesNoCopy(c)
+		if kind == BinKind {
+			return Token{Kind: BinToken, Bytes: b}, err
+		}
+		return Token{Kind: StrToken, Bytes: b}, err
+	case ArrayKind, MapKind:
+		var (
+			n   int
+			err error
+		)
+		if kind == MapKind {
+			n, err = d.mapLen(c)
+		} else {
+			n, err = d.arrayLen(c)
+		}
+		if err != nil {
+			return Token{}, err
+		}
+		if err := d.enter(); err != nil {
+			return Token{}, err
+		}
+		if kind == MapKind {
+			t.stack = append(t.stack, tokenFrame{n: 2 * n, isMap: true})
+			return Token{Kind: MapStartToken, Len: n}, nil
+		}
+		t.stack = append(t.stack, tokenFrame{n: n})
+		return Token{Kind: ArrayStartToken, Len: n}, nil
+	case ExtKind:
+		extID, extLen, err := d.extHeader(c)
+		if err != nil {
+			return Token{}, err
+		}
+		if extID == timeExtID {
+			tm, err := d.decodeTimeExt(extLen)
+			return Token{Kind: TimestampToken, Time: tm}, err
+		}
+		b, err := d.readN(extLen)
+		return Token{Kind: ExtToken, ExtType: extID, Bytes: b}, err
+	}
+	return Token{}, d.codeError(c, "")
+}
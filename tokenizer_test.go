@@ -0,0 +1,106 @@
+package msgpack
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTokenizer(t *testing.T) {
+	tm := time.Unix(1700000000, 5).UTC()
+	data := AppendMapLen(nil, 2)
+	data = AppendString(data, "a")
+	data = Append(data, []interface{}{nil, true, int8(-1), uint8(200), 1.5, []byte{1}, tm})
+	data = AppendString(data, "b")
+	data = Append(data, RawExt{Type: 3, Data: []byte{9}})
+	data = AppendArrayLen(data, 0)
+
+	want := []struct {
+		tok   Token
+		depth int // after the token
+		key   bool
+	}{
+		{Token{Kind: MapStartToken, Len: 2}, 1, false},
+		{Token{Kind: StrToken, Bytes: []byte("a")}, 1, true},
+		{Token{Kind: ArrayStartToken, Len: 7}, 2, false},
+		{Token{Kind: NilToken}, 2, false},
+		{Token{Kind: BoolToken, Bool: true}, 2, false},
+		{Token{Kind: IntToken, Int: -1}, 2, false},
+		{Token{Kind: UintToken, Uint: 200}, 2, false},
+		{Token{Kind: FloatToken, Float: 1.5}, 2, false},
+		{Token{Kind: BinToken, Bytes: []byte{1}}, 2, false},
+		{Token{Kind: TimestampToken, Time: tm}, 2, false},
+		{Token{Kind: ArrayEndToken}, 1, false},
+		{Token{Kind: StrToken, Bytes: []byte("b")}, 1, true},
+		{Token{Kind: ExtToken, ExtType: 3, Bytes: []byte{9}}, 1, false},
+		{Token{Kind: MapEndToken}, 0, false},
+		{Token{Kind: ArrayStartToken}, 1, false},
+		{Token{Kind: ArrayEndToken}, 0, false},
+	}
+
+	d := NewDecoder(data)
+	tz := NewTokenizer(d)
+	for i, w := range want {
+		tok, err := tz.Token()
+		if err != nil {
+			t.Fatalf("token %d: error = %v", i, err)
+		}
+		tok.Time = tok.Time.UTC()
+		if !reflect.DeepEqual(tok, w.tok) {
+			t.Fatalf("token %d = %+v, want %+v", i, tok, w.tok)
+		}
+		if tz.Depth() != w.depth || d.depth != w.depth || tz.IsKey() != w.key {
+			t.Fatalf("token %d: depth %d, decoder depth %d, key %v, want %d, %v",
+				i, tz.Depth(), d.depth, tz.IsKey(), w.depth, w.key)
+		}
+	}
+	if _, err := tz.Token(); err != io.EOF {
+		t.Fatalf("Token() at the end error = %v, want io.EOF", err)
+	}
+}
+
+func TestTokenizerUnwind(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"truncated", []byte{0x92, 0x91, 0x01}, ErrTruncated},
+		{"invalid code", []byte{0x92, 0x91, 0xc1}, ErrUnexpectedCode},
+		{"limit", []byte{0x91, 0x91, 0x91, 0x91, 0x01}, ErrLimitExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(tt.data)
+			d.SetLimits(DecodeLimits{MaxDepth: 3})
+			tz := NewTokenizer(d)
+			var err error
+			for err == nil {
+				_, err = tz.Token()
+			}
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Token() error = %v, want %v", err, tt.err)
+			}
+			if tz.Depth() != 0 || d.depth != 0 {
+				t.Fatalf("after error depth %d, decoder depth %d, want 0", tz.Depth(), d.depth)
+			}
+		})
+	}
+
+	t.Run("reset", func(t *testing.T) {
+		d := NewDecoder([]byte{0x91, 0x91, 0x01})
+		d.SetLimits(DecodeLimits{MaxDepth: 2})
+		tz := NewTokenizer(d)
+		for i := 0; i < 2; i++ {
+			if _, err := tz.Token(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tz.Reset(d)
+		if tz.Depth() != 0 || d.depth != 0 {
+			t.Fatalf("after Reset depth %d, decoder depth %d, want 0", tz.Depth(), d.depth)
+		}
+	})
+}
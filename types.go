@@ -0,0 +1,161 @@
+package msgpack
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var timeType = reflect.TypeOf((*time.Time)(nil)).Elem()
+
+type field struct {
+	name   string
+	index  []int
+	ptr    bool // index goes through an embedded struct pointer
+	tagged bool // name comes from a struct tag
+}
+
+func (f *field) value(strct reflect.Value) reflect.Value {
+	if len(f.index) == 1 {
+		return strct.Field(f.index[0])
+	}
+	return strct.FieldByIndex(f.index)
+}
+
+// lookup is like value but reports false if an embedded struct pointer on the
+// way to the field is nil.
+func (f *field) lookup(strct reflect.Value) (reflect.Value, bool) {
+	if !f.ptr {
+		return f.value(strct), true
+	}
+	v, err := strct.FieldByIndexErr(f.index)
+	return v, err == nil
+}
+
+// settable is like value but allocates nil embedded struct pointers on the way
+// to the field.
+func (f *field) settable(strct reflect.Value) reflect.Value {
+	if !f.ptr {
+		return f.value(strct)
+	}
+	v := strct
+	for i, x := range f.index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+type fields struct {
+	List  []*field
+	Table map[string]*field
+	ptr   bool // some field goes through an embedded struct pointer
+}
+
+var structs sync.Map // map[reflect.Type]*fields
+
+// getFields returns the cached field layout of struct type typ.
+func getFields(typ reflect.Type) *fields {
+	if v, ok := structs.Load(typ); ok {
+		return v.(*fields)
+	}
+	all := collectFields(nil, typ, nil, false, []reflect.Type{typ})
+	fs := &fields{
+		Table: make(map[string]*field, len(all)),
+	}
+	for _, f := range all {
+		if _, ok := fs.Table[f.name]; ok {
+			continue
+		}
+		// Names are resolved at their first appearance, which keeps the order.
+		if f = dominantField(all, f.name); f != nil {
+			fs.List = append(fs.List, f)
+			fs.Table[f.name] = f
+			fs.ptr = fs.ptr || f.ptr
+		}
+	}
+	v, _ := structs.LoadOrStore(typ, fs)
+	return v.(*fields)
+}
+
+// collectFields appends the fields of typ to all. Embedded structs and
+// exported embedded struct pointers are inlined; visiting lists the enclosing
+// struct types to stop at embedding cycles.
+func collectFields(all []*field, typ reflect.Type, index []int, ptr bool, visiting []reflect.Type) []*field {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+
+		tag := f.Tag.Get("msgpack")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft, isPtr := f.Type, false
+			if ft.Kind() == reflect.Pointer {
+				ft, isPtr = ft.Elem(), true
+			}
+			if ft.Kind() == reflect.Struct {
+				// Unexported embedded pointers can't be allocated when decoding.
+				if (!isPtr || f.IsExported()) && !slices.Contains(visiting, ft) {
+					all = collectFields(all, ft, appendIndex(index, i), ptr || isPtr, append(visiting, ft))
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		tagged := name != ""
+		if !tagged {
+			name = f.Name
+		}
+
+		all = append(all, &field{
+			name:   name,
+			index:  appendIndex(index, i),
+			ptr:    ptr,
+			tagged: tagged,
+		})
+	}
+	return all
+}
+
+// dominantField returns the field of all named name that is encoded, following
+// the rules of encoding/json: the least nested fields hide the others, and of
+// several fields at the same depth the only tagged one wins. If there is no
+// single such field, the name is ambiguous and dominantField returns nil.
+func dominantField(all []*field, name string) *field {
+	var (
+		dominant *field
+		n        int // fields as good as dominant
+	)
+	for _, f := range all {
+		switch {
+		case f.name != name:
+		case dominant == nil || len(f.index) < len(dominant.index):
+			dominant, n = f, 1
+		case len(f.index) > len(dominant.index):
+		case f.tagged && !dominant.tagged:
+			dominant, n = f, 1
+		case f.tagged == dominant.tagged:
+			n++
+		}
+	}
+	if n > 1 {
+		return nil
+	}
+	return dominant
+}
+
+func appendIndex(index []int, i int) []int {
+	return append(index[:len(index):len(index)], i)
+}
@@ -0,0 +1,105 @@
+package msgpack_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nurlybekovnt/msgpack"
+)
+
+type embeddedA struct {
+	X string
+	A string
+}
+
+type embeddedB struct {
+	X string
+	B string
+}
+
+type embeddedTagged struct {
+	Y string `msgpack:"X"`
+}
+
+type embeddedTaggedToo struct {
+	Z string `msgpack:"X"`
+}
+
+type embeddedDeep struct {
+	embeddedTagged
+}
+
+func TestEmbeddedFieldConflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want map[string]interface{}
+	}{
+		{
+			"outer field wins",
+			&struct {
+				X string
+				embeddedA
+			}{X: "outer", embeddedA: embeddedA{X: "inner", A: "a"}},
+			map[string]interface{}{"X": "outer", "A": "a"},
+		},
+		{
+			"same depth is ambiguous",
+			&struct {
+				embeddedA
+				embeddedB
+			}{embeddedA{X: "a", A: "a"}, embeddedB{X: "b", B: "b"}},
+			map[string]interface{}{"A": "a", "B": "b"},
+		},
+		{
+			"tagged field wins",
+			&struct {
+				embeddedA
+				embeddedTagged
+			}{embeddedA{X: "a", A: "a"}, embeddedTagged{Y: "tagged"}},
+			map[string]interface{}{"X": "tagged", "A": "a"},
+		},
+		{
+			"two tagged fields are ambiguous",
+			&struct {
+				embeddedTagged
+				embeddedTaggedToo
+			}{embeddedTagged{Y: "y"}, embeddedTaggedToo{Z: "z"}},
+			map[string]interface{}{},
+		},
+		{
+			"ambiguous fields hide deeper ones",
+			&struct {
+				embeddedA
+				embeddedB
+				embeddedDeep
+			}{embeddedA{X: "a"}, embeddedB{X: "b"}, embeddedDeep{embeddedTagged{Y: "deep"}}},
+			map[string]interface{}{"A": "", "B": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := msgpack.Append(nil, tt.v)
+			var got map[string]interface{}
+			if err := msgpack.NewDecoder(b).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Append() = %v, want %v", got, tt.want)
+			}
+
+			// Decoding fills the same fields: the ambiguous ones stay zero.
+			v := reflect.New(reflect.TypeOf(tt.v).Elem()).Interface()
+			if err := msgpack.NewDecoder(b).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+			got = nil
+			if err := msgpack.NewDecoder(msgpack.Append(nil, v)).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() then Append() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package msgpack
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/nurlybekovnt/msgpack/msgpcode"
+)
+
+// Valid checks that b contains exactly one well-formed msgpack value: every
+// code is defined and every length, ext header and timestamp is complete. It
+// doesn't allocate unless b is invalid and doesn't recurse, so deeply nested
+// input can't exhaust the stack.
+func Valid(b []byte) error {
+	d := Decoder{data: b}
+	if err := d.Validate(); err != nil {
+		return d.wrapError(err)
+	}
+	if d.i < len(b) {
+		return d.wrapError(ErrTrailingData)
+	}
+	return nil
+}
+
+// ValidSequence is like Valid but accepts any number of concatenated values,
+// including none.
+func ValidSequence(b []byte) error {
+	d := Decoder{data: b}
+	for d.i < len(b) {
+		if err := d.Validate(); err != nil {
+			return d.wrapError(err)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the next value is well-formed, like Valid, and skips
+// it. Length limits set with SetLimits are enforced; MaxDepth isn't because
+// validation doesn't recurse. Validate returns io.EOF if there is no value.
+func (d *Decoder) Validate() error {
+	c, err := d.readCode()
+	if err != nil {
+		return err
+	}
+
+	// pending is the number of values that are still to be read, including
+	// the current one.
+	for pending := 1; ; {
+		n, err := d.validCode(c)
+		if err != nil {
+			return err
+		}
+		pending += n - 1
+		if pending == 0 {
+			return nil
+		}
+		if d.r == nil && pending > len(d.data)-d.i {
+			// Every value takes at least one byte.
+			return d.wrapError(io.ErrShortBuffer)
+		}
+
+		c, err = d.readCode()
+		if err != nil {
+			return d.wrapError(err)
+		}
+	}
+}
+
+// validCode checks the value starting with code c and skips it, except for
+// the elements of arrays and maps. It returns the number of the elements.
+func (d *Decoder) validCode(c byte) (int, error) {
+	switch {
+	case msgpcode.IsFixedNum(c), c == msgpcode.Nil, c == msgpcode.False, c == msgpcode.True:
+		return 0, nil
+	case msgpcode.IsFixedMap(c), c == msgpcode.Map16, c == msgpcode.Map32:
+		n, err := d.mapLen(c)
+		return 2 * n, err
+	case msgpcode.IsFixedArray(c), c == msgpcode.Array16, c == msgpcode.Array32:
+		return d.arrayLen(c)
+	case msgpcode.IsString(c), msgpcode.IsBin(c):
+		return 0, d.skipBytes(c)
+	case msgpcode.IsExt(c):
+		extID, extLen, err := d.extHeader(c)
+		if err != nil {
+			return 0, err
+		}
+		if extID == -1 && extLen != 4 && extLen != 8 && extLen != 12 {
+			return 0, d.wrapError(fmt.Errorf("msgpack: invalid ext len=%d decoding time", extLen))
+		}
+		return 0, d.skipN(extLen)
+	}
+
+	switch c {
+	case msgpcode.Uint8, msgpcode.Int8:
+		return 0, d.skipN(1)
+	case msgpcode.Uint16, msgpcode.Int16:
+		return 0, d.skipN(2)
+	case msgpcode.Uint32, msgpcode.Int32, msgpcode.Float:
+		return 0, d.skipN(4)
+	case msgpcode.Uint64, msgpcode.Int64, msgpcode.Double:
+		return 0, d.skipN(8)
+	}
+	return 0, d.codeError(c, "")
+}
@@ -0,0 +1,68 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/nurlybekovnt/msgpack"
+)
+
+func TestValid(t *testing.T) {
+	value := msgpack.Append(nil, map[string]interface{}{"a": []interface{}{1, "b", nil}})
+	deep := append(bytes.Repeat([]byte{0x91}, 100000), 0xc0)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		valid  error // error of Valid, nil if valid
+		seq    error // error of ValidSequence, nil if valid
+		offset int   // offset of the ValidSequence error
+	}{
+		{"value", value, nil, nil, 0},
+		{"deep", deep, nil, nil, 0},
+		{"sequence", append(bytes.Clone(value), value...), msgpack.ErrTrailingData, nil, 0},
+		{"empty", nil, msgpack.ErrTruncated, nil, 0},
+		{"truncated", value[:len(value)-1], msgpack.ErrTruncated, msgpack.ErrTruncated, len(value) - 1},
+		{"truncated second", append(bytes.Clone(value), value[:3]...), msgpack.ErrTrailingData, msgpack.ErrTruncated, len(value) + 3},
+		{"never used", []byte{0xc1}, msgpack.ErrUnexpectedCode, msgpack.ErrUnexpectedCode, 1},
+		{"bad timestamp", []byte{0xd5, 0xff, 0, 0}, errTimestamp, errTimestamp, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := func(name string, err, want error) {
+				if want == nil {
+					if err != nil {
+						t.Errorf("%s() error = %v", name, err)
+					}
+					return
+				}
+				var de *msgpack.DecodeError
+				if !errors.As(err, &de) {
+					t.Fatalf("%s() error = %v (%T), want *DecodeError", name, err, err)
+				}
+				if want != errTimestamp && !errors.Is(err, want) {
+					t.Errorf("%s() error = %v, want %v", name, err, want)
+				}
+			}
+			check("Valid", msgpack.Valid(tt.data), tt.valid)
+			check("ValidSequence", msgpack.ValidSequence(tt.data), tt.seq)
+
+			var de *msgpack.DecodeError
+			if errors.As(msgpack.ValidSequence(tt.data), &de) && de.Offset != tt.offset {
+				t.Errorf("ValidSequence() error at offset %d, want %d", de.Offset, tt.offset)
+			}
+		})
+	}
+}
+
+// errTimestamp stands for the error of an invalid timestamp length, which has
+// no sentinel.
+var errTimestamp = errors.New("invalid timestamp")
+
+func TestValidAllocs(t *testing.T) {
+	data := msgpack.Append(nil, map[string]interface{}{"a": []interface{}{1, "b", 1.5, nil}})
+	if n := testing.AllocsPerRun(100, func() { _ = msgpack.Valid(data) }); n != 0 {
+		t.Fatalf("Valid() allocates %v times", n)
+	}
+}
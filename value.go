@@ -0,0 +1,305 @@
+package msgpack
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nurlybekovnt/msgpack/msgpcode"
+)
+
+// ErrNotFound is matched by errors.Is for errors returned by Value when a map
+// key or an array index doesn't exist.
+var ErrNotFound = errors.New("msgpack: value not found")
+
+// Value is a lazy read-only view of an encoded msgpack value. Nothing is
+// decoded until asked for: children are located by skipping over their
+// preceding siblings, and strings and byte slices reference the underlying
+// buffer unless noted otherwise. The buffer must not be modified while the
+// Value is in use.
+//
+// Methods returning a Value don't fail. A missing key, an index out of range
+// or malformed input yield a Value holding the error, which is then returned
+// by Err and by all accessors, so lookups can be chained:
+//
+//	price, err := msgpack.NewValue(b).Get("items").Index(3).Get("price").Int()
+type Value struct {
+	b   []byte // starts with the value, may continue past it
+	off int    // offset of b in the original buffer
+	err error
+}
+
+// NewValue returns a Value for the first msgpack value in b. The input is not
+// validated upfront; use Valid for that.
+func NewValue(b []byte) Value {
+	return Value{b: b}
+}
+
+func errValue(err error) Value {
+	return Value{err: err}
+}
+
+func (v Value) decoder() Decoder {
+	return Decoder{data: v.b, off: v.off}
+}
+
+// value returns a Value for the next value of d.
+func (d *Decoder) value() Value {
+	return Value{b: d.data[d.i:], off: d.offset()}
+}
+
+// Err returns the error of a failed lookup.
+func (v Value) Err() error {
+	return v.err
+}
+
+// Kind returns the kind of the value or InvalidKind if the lookup failed.
+func (v Value) Kind() Kind {
+	if v.err != nil || len(v.b) == 0 {
+		return InvalidKind
+	}
+	return CodeKind(v.b[0])
+}
+
+// Len returns the number of elements of an array, the number of key-value
+// pairs of a map or the length of a string or binary data.
+func (v Value) Len() (int, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+	d := v.decoder()
+	c, err := d.readCode()
+	if err != nil {
+		return 0, d.wrapError(err)
+	}
+	switch CodeKind(c) {
+	case ArrayKind:
+		return d.arrayLen(c)
+	case MapKind:
+		return d.mapLen(c)
+	case StrKind, BinKind:
+		return d.bytesLen(c)
+	}
+	return 0, d.codeError(c, "array, map, str or bin")
+}
+
+// Index returns the i-th element of an array.
+func (v Value) Index(i int) Value {
+	if v.err != nil {
+		return v
+	}
+	d := v.decoder()
+	n, err := d.DecodeArrayLen()
+	if err != nil {
+		return errValue(d.wrapError(err))
+	}
+	if i < 0 || i >= n {
+		return errValue(d.indexError(ErrNotFound, i))
+	}
+	for ; i > 0; i-- {
+		if err := d.Validate(); err != nil {
+			return errValue(d.wrapError(err))
+		}
+	}
+	return d.value()
+}
+
+// Get returns the value of a map for the string key. Keys of other kinds are
+// skipped. If the key occurs more than once, the first occurrence wins.
+func (v Value) Get(key string) Value {
+	if v.err != nil {
+		return v
+	}
+	d := v.decoder()
+	n, err := d.DecodeMapLen()
+	if err != nil {
+		return errValue(d.wrapError(err))
+	}
+	for i := 0; i < n; i++ {
+		c, err := d.readCode()
+		if err != nil {
+			return errValue(d.wrapError(err))
+		}
+		if msgpcode.IsString(c) || msgpcode.IsBin(c) {
+			k, err := d.bytesNoCopy(c)
+			if err != nil {
+				return errValue(err)
+			}
+			if string(k) == key {
+				return d.value()
+			}
+		} else {
+			d.i--
+			if err := d.Validate(); err != nil {
+				return errValue(d.wrapError(err))
+			}
+		}
+		if err := d.Validate(); err != nil {
+			return errValue(d.wrapError(err))
+		}
+	}
+	return errValue(d.keyError(ErrNotFound, key))
+}
+
+// Iterate calls fn for the key-value pairs of a map or the elements of an
+// array, in which case key is the zero Value. Iteration stops when fn returns
+// false.
+func (v Value) Iterate(fn func(key, value Value) bool) error {
+	if v.err != nil {
+		return v.err
+	}
+	d := v.decoder()
+	c, err := d.readCode()
+	if err != nil {
+		return d.wrapError(err)
+	}
+
+	switch CodeKind(c) {
+	case ArrayKind:
+		n, err := d.arrayLen(c)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			elem := d.value()
+			if err := d.Validate(); err != nil {
+				return d.indexError(err, i)
+			}
+			if !fn(Value{}, elem) {
+				return nil
+			}
+		}
+		return nil
+	case MapKind:
+		n, err := d.mapLen(c)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			key := d.value()
+			if err := d.Validate(); err != nil {
+				return d.wrapError(err)
+			}
+			value := d.value()
+			if err := d.Validate(); err != nil {
+				return d.wrapError(err)
+			}
+			if !fn(key, value) {
+				return nil
+			}
+		}
+		return nil
+	}
+	return d.codeError(c, "array or map")
+}
+
+// IsNil reports whether the value is msgpack nil.
+func (v Value) IsNil() bool {
+	return v.Kind() == NilKind
+}
+
+// Bool decodes the value like Decoder.DecodeBool.
+func (v Value) Bool() (bool, error) {
+	if v.err != nil {
+		return false, v.err
+	}
+	d := v.decoder()
+	return d.DecodeBool()
+}
+
+// Int decodes the value like Decoder.DecodeInt64.
+func (v Value) Int() (int64, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+	d := v.decoder()
+	return d.DecodeInt64()
+}
+
+// Uint decodes the value like Decoder.DecodeUint64.
+func (v Value) Uint() (uint64, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+	d := v.decoder()
+	return d.DecodeUint64()
+}
+
+// Float decodes the value like Decoder.DecodeFloat64.
+func (v Value) Float() (float64, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+	d := v.decoder()
+	return d.DecodeFloat64()
+}
+
+// Str returns a copy of a string or binary value.
+func (v Value) Str() (string, error) {
+	b, err := v.StrBytes()
+	return string(b), err
+}
+
+// StrBytes returns a string or binary value without copying it. Unlike
+// Decoder.DecodeBytes it doesn't accept nil.
+func (v Value) StrBytes() ([]byte, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+	d := v.decoder()
+	c, err := d.readCode()
+	if err != nil {
+		return nil, d.wrapError(err)
+	}
+	if !msgpcode.IsString(c) && !msgpcode.IsBin(c) {
+		return nil, d.codeError(c, "str or bin")
+	}
+	return d.bytesNoCopy(c)
+}
+
+// Time decodes the value like Decoder.DecodeTime.
+func (v Value) Time() (time.Time, error) {
+	if v.err != nil {
+		return time.Time{}, v.err
+	}
+	d := v.decoder()
+	return d.DecodeTime()
+}
+
+// Ext returns an ext value. Data references the underlying buffer.
+func (v Value) Ext() (RawExt, error) {
+	if v.err != nil {
+		return RawExt{}, v.err
+	}
+	d := v.decoder()
+	d.flags |= unsafeDecodingFlag
+	c, err := d.readCode()
+	if err != nil {
+		return RawExt{}, d.wrapError(err)
+	}
+	if !msgpcode.IsExt(c) {
+		return RawExt{}, d.codeError(c, "ext")
+	}
+	return d.rawExt(c)
+}
+
+// Raw returns the encoded value without copying it.
+func (v Value) Raw() ([]byte, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+	d := v.decoder()
+	if err := d.Validate(); err != nil {
+		return nil, d.wrapError(err)
+	}
+	return v.b[:d.i], nil
+}
+
+// Decode decodes the value into dst like Decoder.Decode. Strings and byte
+// slices are copied.
+func (v Value) Decode(dst interface{}) error {
+	if v.err != nil {
+		return v.err
+	}
+	d := v.decoder()
+	return d.Decode(dst)
+}
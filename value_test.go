@@ -0,0 +1,79 @@
+package msgpack_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nurlybekovnt/msgpack"
+)
+
+func TestValueStr(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+		err  error
+	}{
+		{"str", msgpack.AppendString(nil, "abc"), "abc", nil},
+		{"empty str", msgpack.AppendString(nil, ""), "", nil},
+		{"bin", msgpack.AppendBytes(nil, []byte("abc")), "abc", nil},
+		{"nil", msgpack.AppendNil(nil), "", msgpack.ErrUnexpectedCode},
+		{"int", msgpack.AppendInt(nil, 1), "", msgpack.ErrUnexpectedCode},
+		{"truncated", msgpack.AppendString(nil, "abc")[:2], "", msgpack.ErrTruncated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := msgpack.NewValue(tt.data).Str()
+			if !errors.Is(err, tt.err) || (tt.err == nil && err != nil) {
+				t.Fatalf("Str() error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Fatalf("Str() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValueLookup(t *testing.T) {
+	data := msgpack.AppendMapSorted(nil, map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"sku": "a", "price": 10},
+			map[string]interface{}{"sku": "b", "price": 20},
+		},
+		"name": "order",
+		"none": nil,
+	})
+	v := msgpack.NewValue(data)
+
+	tests := []struct {
+		name string
+		v    msgpack.Value
+		want interface{}
+		err  error
+	}{
+		{"key", v.Get("name"), "order", nil},
+		{"nested", v.Get("items").Index(1).Get("price"), int8(20), nil},
+		{"nil", v.Get("none"), nil, nil},
+		{"missing key", v.Get("missing"), nil, msgpack.ErrNotFound},
+		{"missing index", v.Get("items").Index(2), nil, msgpack.ErrNotFound},
+		{"not a map", v.Get("name").Get("x"), nil, msgpack.ErrUnexpectedCode},
+		{"error chained", v.Get("missing").Index(0).Get("x"), nil, msgpack.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.v.Err(); !errors.Is(err, tt.err) || (tt.err == nil && err != nil) {
+				t.Fatalf("Err() = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			var got interface{}
+			if err := tt.v.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("Decode() = %v (%T), want %v (%T)", got, got, tt.want, tt.want)
+			}
+		})
+	}
+}